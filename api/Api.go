@@ -0,0 +1,289 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"suricata/config"
+	"suricata/monitor"
+	"time"
+)
+
+// Website registration payload
+type WebsiteRequest struct {
+	Name          string            `json:"name"`
+	Url           string            `json:"url"`
+	CheckInterval int               `json:"checkInterval"`
+	Method        string            `json:"method"`
+	Headers       map[string]string `json:"headers"`
+	Body          string            `json:"body"`
+	Tags          []string          `json:"tags"`
+	Start         *bool             `json:"start"` // Start monitoring right away, true by default
+	// Decoded as in structured config files
+	Type       string                `json:"type"`    // Probe type, the scheme of the url by default, exec excluded
+	Timeout    config.Duration       `json:"timeout"` // Number of ms or duration such as 1.5s, the interval by default
+	Assertions config.SiteAssertions `json:"assertions"`
+	Thresholds []string              `json:"thresholds"` // Alert rules, eg p99>800@medium
+}
+
+// Registered website, as listed by the API
+type WebsiteStatus struct {
+	Name          string   `json:"name,omitempty"`
+	Url           string   `json:"url"`
+	Tags          []string `json:"tags,omitempty"`
+	CheckInterval int      `json:"checkInterval"`
+	Active        bool     `json:"active"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// HTTP handler of the JSON API managing the websites monitored by o
+//
+//	GET    /api/websites               list registered websites
+//	POST   /api/websites               register (and start) a website
+//	DELETE /api/websites?url=...       stop monitoring and unregister a website
+//	POST   /api/websites/pause?url=... pause monitoring a website
+//	POST   /api/websites/resume?url=.. resume monitoring a website
+//	GET    /api/websites/report?url=.. get the report of a website
+func Handler(o *monitor.Orchestrator) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/websites", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			list(w, o)
+		case http.MethodPost:
+			add(w, r, o)
+		case http.MethodDelete:
+			remove(w, r, o)
+		default:
+			methodNotAllowed(w, "GET, POST, DELETE")
+		}
+	})
+	mux.HandleFunc("/api/websites/pause", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, "POST")
+			return
+		}
+		pause(w, r, o)
+	})
+	mux.HandleFunc("/api/websites/resume", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, "POST")
+			return
+		}
+		resume(w, r, o)
+	})
+	mux.HandleFunc("/api/websites/report", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, "GET")
+			return
+		}
+		report(w, r, o)
+	})
+	return mux
+}
+
+func list(w http.ResponseWriter, o *monitor.Orchestrator) {
+	websites := make([]WebsiteStatus, 0)
+	for _, url := range o.GetUrls() {
+		report := o.GetReport(url)
+		if report == nil {
+			continue
+		}
+		websites = append(websites, WebsiteStatus{
+			Name:          report.Name,
+			Url:           report.Url,
+			Tags:          report.Tags,
+			CheckInterval: report.CheckInterval,
+			Active:        report.Active,
+		})
+	}
+	writeJSON(w, http.StatusOK, websites)
+}
+
+func add(w http.ResponseWriter, r *http.Request, o *monitor.Orchestrator) {
+	var req WebsiteRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID JSON BODY: "+err.Error())
+		return
+	}
+	if req.Url == "" {
+		writeError(w, http.StatusBadRequest, "URL IS REQUIRED")
+		return
+	}
+	if !strings.Contains(req.Url, "://") {
+		req.Url = "http://" + req.Url
+	}
+	if req.CheckInterval < 0 {
+		writeError(w, http.StatusBadRequest, "INTERVAL MUST BE POSITIVE")
+		return
+	}
+	if req.CheckInterval == 0 {
+		req.CheckInterval = config.DEFAULT_CHECKING_INTERVAL
+	}
+	if req.Timeout < 0 {
+		writeError(w, http.StatusBadRequest, "TIMEOUT MUST BE POSITIVE")
+		return
+	}
+	if o.GetReport(req.Url) != nil {
+		writeError(w, http.StatusConflict, "WEBSITE "+req.Url+" ALREADY REGISTERED")
+		return
+	}
+
+	website := monitor.Website{
+		Name:          req.Name,
+		Url:           req.Url,
+		Type:          strings.ToLower(req.Type),
+		CheckInterval: req.CheckInterval,
+		Timeout:       time.Duration(req.Timeout),
+		Method:        req.Method,
+		Headers:       req.Headers,
+		Body:          req.Body,
+		Tags:          req.Tags,
+	}
+	// The API is not authenticated, commands are only run for websites of the config file
+	if monitor.ProbeType(website) == monitor.PROBE_EXEC {
+		writeError(w, http.StatusBadRequest, "EXEC WEBSITES CAN ONLY BE DEFINED IN THE CONFIG FILE")
+		return
+	}
+	website.Assertions, err = req.Assertions.Assertions()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID ASSERTIONS: "+err.Error())
+		return
+	}
+	for _, threshold := range req.Thresholds {
+		rule, err := monitor.ParseAlertRule(threshold)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		website.AlertRules = append(website.AlertRules, rule)
+	}
+	err = o.Register(website)
+	if err != nil {
+		// Registered concurrently, or rejected, eg for an unknown type or a rule over an unknown window
+		status := http.StatusBadRequest
+		if o.GetReport(website.Url) != nil {
+			status = http.StatusConflict
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+	active := false
+	if req.Start == nil || *req.Start {
+		_, err = o.Start(website.Url)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		active = true
+	}
+	writeJSON(w, http.StatusCreated, WebsiteStatus{
+		Name:          website.Name,
+		Url:           website.Url,
+		Tags:          website.Tags,
+		CheckInterval: website.CheckInterval,
+		Active:        active,
+	})
+}
+
+func remove(w http.ResponseWriter, r *http.Request, o *monitor.Orchestrator) {
+	url, ok := registeredUrl(w, r, o)
+	if !ok {
+		return
+	}
+	_, err := o.Pause(url)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	err = o.Unregister(url)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func pause(w http.ResponseWriter, r *http.Request, o *monitor.Orchestrator) {
+	url, ok := registeredUrl(w, r, o)
+	if !ok {
+		return
+	}
+	_, err := o.Pause(url)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeStatus(w, o, url)
+}
+
+func resume(w http.ResponseWriter, r *http.Request, o *monitor.Orchestrator) {
+	url, ok := registeredUrl(w, r, o)
+	if !ok {
+		return
+	}
+	_, err := o.Start(url)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeStatus(w, o, url)
+}
+
+func report(w http.ResponseWriter, r *http.Request, o *monitor.Orchestrator) {
+	url, ok := registeredUrl(w, r, o)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, o.GetReport(url))
+}
+
+// Read the url query parameter, and check the website is registered
+func registeredUrl(w http.ResponseWriter, r *http.Request, o *monitor.Orchestrator) (string, bool) {
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		writeError(w, http.StatusBadRequest, "URL PARAMETER IS REQUIRED")
+		return "", false
+	}
+	if o.GetReport(url) == nil {
+		writeError(w, http.StatusNotFound, "WEBSITE "+url+" IS NOT REGISTERED")
+		return "", false
+	}
+	return url, true
+}
+
+func writeStatus(w http.ResponseWriter, o *monitor.Orchestrator, url string) {
+	report := o.GetReport(url)
+	if report == nil {
+		writeError(w, http.StatusNotFound, "WEBSITE "+url+" IS NOT REGISTERED")
+		return
+	}
+	writeJSON(w, http.StatusOK, WebsiteStatus{
+		Name:          report.Name,
+		Url:           report.Url,
+		Tags:          report.Tags,
+		CheckInterval: report.CheckInterval,
+		Active:        report.Active,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeError(w, http.StatusMethodNotAllowed, "METHOD NOT ALLOWED")
+}
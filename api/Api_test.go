@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"suricata/monitor"
+	"testing"
+	"time"
+)
+
+// Orchestrator of a single test, closed at its end
+func newOrchestrator(t *testing.T) *monitor.Orchestrator {
+	o, err := monitor.NewOrchestrator(monitor.OrchestratorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go o.Run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		o.Close()
+	})
+	return o
+}
+
+func TestApi_Lifecycle(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	server := httptest.NewServer(Handler(newOrchestrator(t)))
+	defer server.Close()
+	query := "?url=" + url.QueryEscape(target.URL)
+
+	// Add
+	res, err := http.Post(server.URL+"/api/websites", "application/json",
+		strings.NewReader(`{"url":"`+target.URL+`","checkInterval":100}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusCreated {
+		t.Error("Expected status 201 on add, got", res.StatusCode)
+	}
+	res, _ = http.Post(server.URL+"/api/websites", "application/json",
+		strings.NewReader(`{"url":"`+target.URL+`"}`))
+	if res.StatusCode != http.StatusConflict {
+		t.Error("Expected status 409 on second add, got", res.StatusCode)
+	}
+
+	// List
+	var websites []WebsiteStatus
+	res, _ = http.Get(server.URL + "/api/websites")
+	json.NewDecoder(res.Body).Decode(&websites)
+	res.Body.Close()
+	if len(websites) != 1 || websites[0].Url != target.URL || !websites[0].Active || websites[0].CheckInterval != 100 {
+		t.Error("Listed websites do not match expectation:", websites)
+	}
+
+	// Pause
+	var status WebsiteStatus
+	res, _ = http.Post(server.URL+"/api/websites/pause"+query, "", nil)
+	json.NewDecoder(res.Body).Decode(&status)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || status.Active {
+		t.Error("Website was not paused:", res.StatusCode, status)
+	}
+
+	// Resume
+	res, _ = http.Post(server.URL+"/api/websites/resume"+query, "", nil)
+	json.NewDecoder(res.Body).Decode(&status)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || !status.Active {
+		t.Error("Website was not resumed:", res.StatusCode, status)
+	}
+
+	// Report
+	var report monitor.Report
+	res, _ = http.Get(server.URL + "/api/websites/report" + query)
+	json.NewDecoder(res.Body).Decode(&report)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || report.Url != target.URL {
+		t.Error("Report does not match expectation:", res.StatusCode, report)
+	}
+
+	// Remove
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/websites"+query, nil)
+	res, _ = http.DefaultClient.Do(req)
+	if res.StatusCode != http.StatusNoContent {
+		t.Error("Expected status 204 on remove, got", res.StatusCode)
+	}
+	res, _ = http.Get(server.URL + "/api/websites/report" + query)
+	if res.StatusCode != http.StatusNotFound {
+		t.Error("Expected status 404 on removed website, got", res.StatusCode)
+	}
+}
+
+func TestApi_InvalidRequests(t *testing.T) {
+	server := httptest.NewServer(Handler(newOrchestrator(t)))
+	defer server.Close()
+
+	res, _ := http.Post(server.URL+"/api/websites", "application/json", strings.NewReader(`{"checkInterval":100}`))
+	if res.StatusCode != http.StatusBadRequest {
+		t.Error("Expected status 400 without url, got", res.StatusCode)
+	}
+	res, _ = http.Post(server.URL+"/api/websites", "application/json", strings.NewReader(`not json`))
+	if res.StatusCode != http.StatusBadRequest {
+		t.Error("Expected status 400 on invalid JSON, got", res.StatusCode)
+	}
+	res, _ = http.Post(server.URL+"/api/websites/pause", "", nil)
+	if res.StatusCode != http.StatusBadRequest {
+		t.Error("Expected status 400 without url parameter, got", res.StatusCode)
+	}
+	res, _ = http.Get(server.URL + "/api/websites/pause?url=x")
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Error("Expected status 405, got", res.StatusCode)
+	}
+}
+
+func TestApi_WebsiteOptions(t *testing.T) {
+	o := newOrchestrator(t)
+	server := httptest.NewServer(Handler(o))
+	defer server.Close()
+
+	body := `{"url":"https://www.example.com","start":false,"type":"HTTP","timeout":"1.5s",
+		"assertions":{"status":["200-299"],"contains":["ok"],"json":{"status":"up"}},
+		"thresholds":["availability<0.9@short"]}`
+	res, _ := http.Post(server.URL+"/api/websites", "application/json", strings.NewReader(body))
+	if res.StatusCode != http.StatusCreated {
+		t.Fatal("Expected status 201 on add, got", res.StatusCode)
+	}
+	website, err := o.GetWebsite("https://www.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if website.Type != "http" || website.Timeout != 1500*time.Millisecond {
+		t.Error("Unexpected type or timeout", website.Type, website.Timeout)
+	}
+	if len(website.Assertions.Status) != 1 || website.Assertions.Contains[0] != "ok" || website.Assertions.JSONPaths["status"] != "up" {
+		t.Error("Assertions do not match expectation:", website.Assertions)
+	}
+	if len(website.AlertRules) != 1 || website.AlertRules[0].Metric != monitor.METRIC_AVAILABILITY {
+		t.Error("Alert rules do not match expectation:", website.AlertRules)
+	}
+
+	invalid := []string{
+		`{"url":"https://invalid.example.com","interval":"1s"}`,
+		`{"url":"https://invalid.example.com","timeout":-1}`,
+		`{"url":"https://invalid.example.com","assertions":{"status":["abc"]}}`,
+		`{"url":"https://invalid.example.com","thresholds":["availability"]}`,
+		`{"url":"https://invalid.example.com","thresholds":["availability<0.9@unknown"]}`,
+		`{"url":"https://invalid.example.com","type":"amqp"}`,
+		`{"url":"https://invalid.example.com","type":"EXEC"}`,
+		`{"url":"exec://invalid","command":["touch","/tmp/invalid"]}`,
+		`{"url":"Exec://invalid"}`,
+	}
+	for _, body := range invalid {
+		res, _ = http.Post(server.URL+"/api/websites", "application/json", strings.NewReader(body))
+		if res.StatusCode != http.StatusBadRequest {
+			t.Error("Expected status 400 for", body, "got", res.StatusCode)
+		}
+	}
+	if len(o.GetUrls()) != 1 {
+		t.Error("Invalid websites were registered:", o.GetUrls())
+	}
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"suricata/monitor"
+	"time"
+)
+
+// Check interval of websites not defining one, in ms
+const DEFAULT_CHECKING_INTERVAL int = 1000
+
+// Read the websites to monitor from a config file
+// .yaml, .yml and .json files are structured configs, any other file is read as legacy CSV lines
+func Load(fileLocation string) ([]monitor.Website, error) {
+	switch strings.ToLower(filepath.Ext(fileLocation)) {
+	case ".yaml", ".yml":
+		return loadStructured(fileLocation, FORMAT_YAML)
+	case ".json":
+		return loadStructured(fileLocation, FORMAT_JSON)
+	default:
+		return loadCSV(fileLocation)
+	}
+}
+
+// Read a config file made of url[,interval[,key=value...]] lines
+func loadCSV(fileLocation string) ([]monitor.Website, error) {
+	file, err := os.Open(fileLocation)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	websites := make([]monitor.Website, 0)
+	foundUrls := make(map[string]bool)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var interval int
+
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		params := splitConfigLine(line)
+		url := normalizeUrl(params[0])
+
+		if len(params) >= 2 && params[1] != "" {
+			interval, err = strconv.Atoi(params[1])
+			if err != nil {
+				return nil, errors.New("INVALID CONFIG FILE: INTERVAL MUST BE INTEGER")
+			}
+			if interval <= 0 {
+				return nil, errors.New("INVALID CONFIG FILE: INTERVAL OF " + url + " MUST BE POSITIVE")
+			}
+		} else {
+			interval = DEFAULT_CHECKING_INTERVAL
+		}
+
+		website := monitor.Website{Url: url, CheckInterval: interval}
+		if len(params) > 2 {
+			err = parseOptions(&website, params[2:])
+			if err != nil {
+				return nil, err
+			}
+		}
+		if _, ok := foundUrls[url]; !ok {
+			websites = append(websites, website)
+			foundUrls[url] = true
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return websites, nil
+}
+
+// Prefix urls without a scheme with http://, other schemes select other probes, eg tcp://
+func normalizeUrl(url string) string {
+	if !strings.Contains(url, "://") {
+		return "http://" + url
+	}
+	return url
+}
+
+// Apply the optional key=value fields of a config line to website
+func parseOptions(website *monitor.Website, options []string) error {
+	for _, option := range options {
+		kv := strings.SplitN(option, "=", 2)
+		if len(kv) != 2 {
+			return errors.New("INVALID CONFIG FILE: OPTIONS MUST BE key=value, GOT " + option)
+		}
+		key, value := strings.TrimSpace(kv[0]), kv[1]
+		switch key {
+		case "type":
+			website.Type = strings.ToLower(strings.TrimSpace(value))
+		case "command":
+			website.Command = strings.Fields(value)
+		case "method":
+			website.Method = strings.ToUpper(strings.TrimSpace(value))
+		case "header":
+			header := strings.SplitN(value, ":", 2)
+			if len(header) != 2 {
+				return errors.New("INVALID CONFIG FILE: HEADERS MUST BE Name: value, GOT " + value)
+			}
+			if website.Headers == nil {
+				website.Headers = make(map[string]string)
+			}
+			website.Headers[strings.TrimSpace(header[0])] = strings.TrimSpace(header[1])
+		case "body":
+			website.Body = value
+		case "body_file":
+			content, err := ioutil.ReadFile(value)
+			if err != nil {
+				return err
+			}
+			website.Body = string(content)
+		case "status":
+			err := addStatus(&website.Assertions, strings.Split(value, "|"))
+			if err != nil {
+				return err
+			}
+		case "contains":
+			website.Assertions.Contains = append(website.Assertions.Contains, value)
+		case "not_contains":
+			website.Assertions.NotContains = append(website.Assertions.NotContains, value)
+		case "matches", "not_matches":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return err
+			}
+			if key == "matches" {
+				website.Assertions.Matches = append(website.Assertions.Matches, re)
+			} else {
+				website.Assertions.NotMatches = append(website.Assertions.NotMatches, re)
+			}
+		case "json":
+			pathValue := strings.SplitN(value, "=", 2)
+			if len(pathValue) != 2 {
+				return errors.New("INVALID CONFIG FILE: JSON ASSERTIONS MUST BE path=value, GOT " + value)
+			}
+			if website.Assertions.JSONPaths == nil {
+				website.Assertions.JSONPaths = make(map[string]string)
+			}
+			website.Assertions.JSONPaths[pathValue[0]] = pathValue[1]
+		case "max_body":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return errors.New("INVALID CONFIG FILE: MAX BODY SIZE MUST BE INTEGER")
+			}
+			website.Assertions.MaxBodySize = size
+		case "alert":
+			err := addAlertRules(website, []string{value})
+			if err != nil {
+				return err
+			}
+		case "windows":
+			err := setWindows(website, strings.Split(value, "|"))
+			if err != nil {
+				return err
+			}
+		case "retries", "confirm":
+			count, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || count < 0 {
+				return errors.New("INVALID CONFIG FILE: " + strings.ToUpper(key) + " MUST BE A POSITIVE INTEGER")
+			}
+			if key == "retries" {
+				website.Retries = count
+			} else {
+				website.ConfirmAfter = count
+			}
+		case "retry_delay":
+			delay, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || delay < 0 {
+				return errors.New("INVALID CONFIG FILE: RETRY DELAY MUST BE A POSITIVE NUMBER OF MILLISECONDS")
+			}
+			website.RetryDelay = time.Duration(delay) * time.Millisecond
+		case "cert_expiry":
+			for _, str := range strings.Split(value, "|") {
+				days, err := strconv.Atoi(strings.TrimSpace(str))
+				if err != nil {
+					return errors.New("INVALID CONFIG FILE: CERTIFICATE EXPIRY DAYS MUST BE INTEGERS")
+				}
+				website.CertExpiry = append(website.CertExpiry, days)
+			}
+		default:
+			return errors.New("INVALID CONFIG FILE: UNKNOWN OPTION " + key)
+		}
+	}
+	return nil
+}
+
+// Append the status codes or ranges to the accepted ones
+func addStatus(assertions *monitor.Assertions, values []string) error {
+	for _, str := range values {
+		statusRange, err := monitor.ParseStatusRange(str)
+		if err != nil {
+			return err
+		}
+		assertions.Status = append(assertions.Status, statusRange)
+	}
+	return nil
+}
+
+// Append the parsed alert rules to the website's
+func addAlertRules(website *monitor.Website, values []string) error {
+	for _, value := range values {
+		rule, err := monitor.ParseAlertRule(value)
+		if err != nil {
+			return errors.New("INVALID CONFIG FILE: " + err.Error())
+		}
+		website.AlertRules = append(website.AlertRules, rule)
+	}
+	return nil
+}
+
+// Replace the website's aggregation windows with the parsed ones
+func setWindows(website *monitor.Website, values []string) error {
+	siteWindows, err := monitor.ParseWindows(values)
+	if err != nil {
+		return errors.New("INVALID CONFIG FILE: " + err.Error())
+	}
+	website.Windows = siteWindows
+	return nil
+}
+
+// Split a config line on commas, commas escaped with a backslash are kept
+func splitConfigLine(line string) []string {
+	params := make([]string, 0)
+	var current strings.Builder
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			if r != ',' {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ',':
+			params = append(params, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if escaped {
+		current.WriteRune('\\')
+	}
+	return append(params, current.String())
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Write content to a temporary file named name, returns its path
+func writeConfig(t *testing.T, name string, content string) string {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	err = ioutil.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfig_LoadCSV(t *testing.T) {
+	path := writeConfig(t, "config.sample", "www.google.com,300\n"+
+		"https://github.com,500,method=post,header=Accept: text/html,alert=p99>800@medium,cert_expiry=14|3,retries=2,retry_delay=50,confirm=3\n"+
+		"\n"+
+		"https://github.com,200\n"+
+		"https://example.com\n"+
+		"tcp://db.example.com:5432,1000,type=TCP\n"+
+		"exec://orders-queue,5000,command=/usr/local/bin/check-queue  --max 100\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	websites, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(websites) != 5 {
+		t.Fatal("Expected 5 websites, blank lines and duplicates skipped, got", len(websites))
+	}
+	if websites[0].Url != "http://www.google.com" || websites[0].CheckInterval != 300 {
+		t.Error("Unexpected first website", websites[0])
+	}
+	github := websites[1]
+	if github.CheckInterval != 500 || github.Method != "POST" || github.Headers["Accept"] != "text/html" {
+		t.Error("Options of the second line not applied", github)
+	}
+	if len(github.AlertRules) != 1 || github.AlertRules[0].Window != "medium" {
+		t.Error("Expected the alert rule of the second line", github.AlertRules)
+	}
+	if github.Retries != 2 || github.RetryDelay != 50*time.Millisecond || github.ConfirmAfter != 3 {
+		t.Error("Expected the retries of the second line", github.Retries, github.RetryDelay, github.ConfirmAfter)
+	}
+	if len(github.CertExpiry) != 2 || github.CertExpiry[1] != 3 {
+		t.Error("Expected the certificate expiry thresholds of the second line", github.CertExpiry)
+	}
+	if websites[2].CheckInterval != DEFAULT_CHECKING_INTERVAL {
+		t.Error("Expected the default interval, got", websites[2].CheckInterval)
+	}
+	if websites[3].Url != "tcp://db.example.com:5432" || websites[3].Type != "tcp" {
+		t.Error("Expected the tcp scheme and type to be kept, got", websites[3].Url, websites[3].Type)
+	}
+	if len(websites[4].Command) != 3 || websites[4].Command[2] != "100" {
+		t.Error("Expected the command to be split into arguments, got", websites[4].Command)
+	}
+}
+
+func TestConfig_LoadCSVErrors(t *testing.T) {
+	lines := []string{
+		"https://github.com,fast",
+		"https://github.com,0",
+		"https://github.com,-500",
+		"https://github.com,500,method",
+		"https://github.com,500,unknown=1",
+		"https://github.com,500,alert=availability",
+		"https://github.com,500,cert_expiry=30|soon",
+		"https://github.com,500,retries=-1",
+		"https://github.com,500,retry_delay=1s",
+	}
+	for _, line := range lines {
+		path := writeConfig(t, "config.csv", line)
+		_, err := Load(path)
+		if err == nil {
+			t.Error("Expected an error for", line)
+		}
+		os.RemoveAll(filepath.Dir(path))
+	}
+	_, err := Load("/does/not/exist")
+	if err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestConfig_SplitConfigLine(t *testing.T) {
+	params := splitConfigLine(`https://github.com,500,contains=a\,b,matches=\d+`)
+	expected := []string{"https://github.com", "500", "contains=a,b", `matches=\d+`}
+	if len(params) != len(expected) {
+		t.Fatal("Expected", expected, "got", params)
+	}
+	for idx := range expected {
+		if params[idx] != expected[idx] {
+			t.Error("Expected", expected[idx], "got", params[idx])
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"suricata/monitor"
+	"time"
+)
+
+const (
+	FORMAT_YAML = "yaml"
+	FORMAT_JSON = "json"
+)
+
+// Structured config file, defaults apply to every website not overriding them
+type File struct {
+	Defaults Site   `yaml:"defaults" json:"defaults"`
+	Websites []Site `yaml:"websites" json:"websites"`
+}
+
+// Block of a structured config describing a website, or the defaults of all websites
+type Site struct {
+	Name       string            `yaml:"name" json:"name"`
+	Url        string            `yaml:"url" json:"url"`
+	Type       string            `yaml:"type" json:"type"`       // Probe type, the scheme of the url by default
+	Command    []string          `yaml:"command" json:"command"` // Command run by exec websites, and its arguments
+	Interval   Duration          `yaml:"interval" json:"interval"`
+	Timeout    Duration          `yaml:"timeout" json:"timeout"`
+	Method     string            `yaml:"method" json:"method"`
+	Headers    map[string]string `yaml:"headers" json:"headers"`
+	Body       string            `yaml:"body" json:"body"`
+	BodyFile   string            `yaml:"body_file" json:"body_file"`
+	Tags       []string          `yaml:"tags" json:"tags"`
+	Thresholds []string          `yaml:"thresholds" json:"thresholds"` // Alert rules, eg p99>800@medium
+	Windows    []string          `yaml:"windows" json:"windows"`
+	CertExpiry []int             `yaml:"cert_expiry" json:"cert_expiry"` // Days to certificate expiry alerts are raised at
+	Retries    int               `yaml:"retries" json:"retries"`         // Attempts made again within a check while unsuccessful
+	RetryDelay Duration          `yaml:"retry_delay" json:"retry_delay"`
+	Confirm    int               `yaml:"confirm" json:"confirm"` // Consecutive unsuccessful checks before they count as down
+	Assertions SiteAssertions    `yaml:"assertions" json:"assertions"`
+}
+
+// Assertions block of a site, each field replaces the default one when set
+type SiteAssertions struct {
+	Status      []string          `yaml:"status" json:"status"`
+	Contains    []string          `yaml:"contains" json:"contains"`
+	NotContains []string          `yaml:"not_contains" json:"not_contains"`
+	Matches     []string          `yaml:"matches" json:"matches"`
+	NotMatches  []string          `yaml:"not_matches" json:"not_matches"`
+	JSON        map[string]string `yaml:"json" json:"json"`
+	MaxBody     int64             `yaml:"max_body" json:"max_body"`
+}
+
+// Duration given either as a number of ms or as a string such as 1.5s
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+	return d.set(value)
+}
+
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value interface{}
+	err := unmarshal(&value)
+	if err != nil {
+		return err
+	}
+	return d.set(value)
+}
+
+func (d *Duration) set(value interface{}) error {
+	switch v := value.(type) {
+	case int:
+		*d = Duration(time.Duration(v) * time.Millisecond)
+	case float64:
+		*d = Duration(time.Duration(v * float64(time.Millisecond)))
+	case string:
+		if ms, err := strconv.Atoi(v); err == nil {
+			*d = Duration(time.Duration(ms) * time.Millisecond)
+			return nil
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return errors.New("INVALID CONFIG FILE: INVALID DURATION " + v)
+		}
+		*d = Duration(parsed)
+	default:
+		return errors.New("INVALID CONFIG FILE: DURATIONS MUST BE ms OR STRINGS SUCH AS 1s")
+	}
+	return nil
+}
+
+// Read a YAML or JSON config file
+func loadStructured(fileLocation string, format string) ([]monitor.Website, error) {
+	content, err := ioutil.ReadFile(fileLocation)
+	if err != nil {
+		return nil, err
+	}
+	return parseStructured(content, format)
+}
+
+func parseStructured(content []byte, format string) ([]monitor.Website, error) {
+	var file File
+	var err error
+	switch format {
+	case FORMAT_YAML:
+		err = yaml.UnmarshalStrict(content, &file)
+	case FORMAT_JSON:
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&file)
+	default:
+		return nil, errors.New("UNKNOWN CONFIG FORMAT " + format)
+	}
+	if err != nil {
+		return nil, errors.New("INVALID CONFIG FILE: " + err.Error())
+	}
+
+	websites := make([]monitor.Website, 0, len(file.Websites))
+	foundUrls := make(map[string]bool)
+	for _, site := range file.Websites {
+		website, err := file.Defaults.merge(site).website()
+		if err != nil {
+			return nil, err
+		}
+		if !foundUrls[website.Url] {
+			websites = append(websites, website)
+			foundUrls[website.Url] = true
+		}
+	}
+	return websites, nil
+}
+
+// Site with the unset fields of site taken from the defaults d
+// Headers are merged and tags appended to the default ones
+func (d Site) merge(site Site) Site {
+	merged := site
+	if merged.Interval == 0 {
+		merged.Interval = d.Interval
+	}
+	if merged.Timeout == 0 {
+		merged.Timeout = d.Timeout
+	}
+	if merged.Method == "" {
+		merged.Method = d.Method
+	}
+	if merged.Body == "" && merged.BodyFile == "" {
+		merged.Body = d.Body
+		merged.BodyFile = d.BodyFile
+	}
+	if len(d.Headers) > 0 {
+		merged.Headers = make(map[string]string)
+		for name, value := range d.Headers {
+			merged.Headers[name] = value
+		}
+		for name, value := range site.Headers {
+			merged.Headers[name] = value
+		}
+	}
+	if len(d.Tags) > 0 {
+		merged.Tags = append(append([]string{}, d.Tags...), site.Tags...)
+	}
+	if len(merged.Thresholds) == 0 {
+		merged.Thresholds = d.Thresholds
+	}
+	if len(merged.Windows) == 0 {
+		merged.Windows = d.Windows
+	}
+	if len(merged.CertExpiry) == 0 {
+		merged.CertExpiry = d.CertExpiry
+	}
+	if merged.Retries == 0 {
+		merged.Retries = d.Retries
+	}
+	if merged.RetryDelay == 0 {
+		merged.RetryDelay = d.RetryDelay
+	}
+	if merged.Confirm == 0 {
+		merged.Confirm = d.Confirm
+	}
+
+	a, defaults := &merged.Assertions, d.Assertions
+	if len(a.Status) == 0 {
+		a.Status = defaults.Status
+	}
+	if len(a.Contains) == 0 {
+		a.Contains = defaults.Contains
+	}
+	if len(a.NotContains) == 0 {
+		a.NotContains = defaults.NotContains
+	}
+	if len(a.Matches) == 0 {
+		a.Matches = defaults.Matches
+	}
+	if len(a.NotMatches) == 0 {
+		a.NotMatches = defaults.NotMatches
+	}
+	if len(a.JSON) == 0 {
+		a.JSON = defaults.JSON
+	}
+	if a.MaxBody == 0 {
+		a.MaxBody = defaults.MaxBody
+	}
+	return merged
+}
+
+// Website described by the site block
+func (s Site) website() (monitor.Website, error) {
+	if s.Url == "" {
+		return monitor.Website{}, errors.New("INVALID CONFIG FILE: URL IS REQUIRED")
+	}
+	website := monitor.Website{
+		Name:          s.Name,
+		Url:           normalizeUrl(s.Url),
+		Type:          strings.ToLower(s.Type),
+		CheckInterval: DEFAULT_CHECKING_INTERVAL,
+		Timeout:       time.Duration(s.Timeout),
+		Method:        strings.ToUpper(s.Method),
+		Headers:       s.Headers,
+		Body:          s.Body,
+		Command:       s.Command,
+		Tags:          s.Tags,
+		CertExpiry:    s.CertExpiry,
+		Retries:       s.Retries,
+		RetryDelay:    time.Duration(s.RetryDelay),
+		ConfirmAfter:  s.Confirm,
+	}
+	if s.Interval != 0 {
+		website.CheckInterval = int(time.Duration(s.Interval) / time.Millisecond)
+	}
+	if website.CheckInterval <= 0 {
+		return monitor.Website{}, errors.New("INVALID CONFIG FILE: INTERVAL OF " + website.Url + " MUST BE AT LEAST 1ms")
+	}
+	if website.Timeout < 0 {
+		return monitor.Website{}, errors.New("INVALID CONFIG FILE: TIMEOUT OF " + website.Url + " MUST BE POSITIVE")
+	}
+	if s.BodyFile != "" {
+		content, err := ioutil.ReadFile(s.BodyFile)
+		if err != nil {
+			return monitor.Website{}, err
+		}
+		website.Body = string(content)
+	}
+
+	assertions, err := s.Assertions.Assertions()
+	if err != nil {
+		return monitor.Website{}, err
+	}
+	website.Assertions = assertions
+
+	err = addAlertRules(&website, s.Thresholds)
+	if err != nil {
+		return monitor.Website{}, err
+	}
+	if len(s.Windows) > 0 {
+		err = setWindows(&website, s.Windows)
+		if err != nil {
+			return monitor.Website{}, err
+		}
+	}
+	return website, nil
+}
+
+// Assertions described by the block, status codes parsed and regular expressions compiled
+func (a SiteAssertions) Assertions() (monitor.Assertions, error) {
+	assertions := monitor.Assertions{
+		Contains:    a.Contains,
+		NotContains: a.NotContains,
+		JSONPaths:   a.JSON,
+		MaxBodySize: a.MaxBody,
+	}
+	err := addStatus(&assertions, a.Status)
+	if err != nil {
+		return monitor.Assertions{}, err
+	}
+	assertions.Matches, err = compileAll(a.Matches)
+	if err != nil {
+		return monitor.Assertions{}, err
+	}
+	assertions.NotMatches, err = compileAll(a.NotMatches)
+	if err != nil {
+		return monitor.Assertions{}, err
+	}
+	return assertions, nil
+}
+
+// Compile regular expressions, nil if there are none
+func compileAll(exprs []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const yamlConfig = `
+defaults:
+  interval: 2s
+  timeout: 1500ms
+  headers:
+    User-Agent: suricata
+  tags: [prod]
+  thresholds: ["availability<=0.8"]
+  retries: 2
+  retry_delay: 100ms
+websites:
+  - name: github
+    url: https://github.com
+    interval: 500
+    method: post
+    headers:
+      Accept: application/json
+    tags: [team-a]
+    cert_expiry: [14, 3]
+    confirm: 3
+    thresholds: ["p99>800@medium", "error_rate>0.1"]
+    windows: [short=2m, medium=10m]
+    assertions:
+      status: [200-299, 301]
+      contains: [ok]
+      json:
+        data.status: ok
+  - url: www.google.com
+`
+
+const jsonConfig = `{
+  "defaults": {"interval": "2s", "tags": ["prod"]},
+  "websites": [
+    {"name": "github", "url": "https://github.com", "timeout": 800, "thresholds": ["p99>800@medium"]},
+    {"url": "www.google.com", "headers": {"Accept": "text/html"}}
+  ]
+}`
+
+func TestStructured_YAML(t *testing.T) {
+	path := writeConfig(t, "suricata.yaml", yamlConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	websites, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(websites) != 2 {
+		t.Fatal("Expected 2 websites, got", len(websites))
+	}
+
+	github := websites[0]
+	if github.Name != "github" || github.Url != "https://github.com" || github.Method != "POST" {
+		t.Error("Unexpected website", github)
+	}
+	if github.CheckInterval != 500 {
+		t.Error("Expected an interval of 500 ms, got", github.CheckInterval)
+	}
+	if github.Timeout != 1500*time.Millisecond {
+		t.Error("Expected the default timeout, got", github.Timeout)
+	}
+	if github.Headers["User-Agent"] != "suricata" || github.Headers["Accept"] != "application/json" {
+		t.Error("Expected headers merged with the default ones, got", github.Headers)
+	}
+	if len(github.Tags) != 2 || github.Tags[0] != "prod" || github.Tags[1] != "team-a" {
+		t.Error("Expected tags appended to the default ones, got", github.Tags)
+	}
+	if len(github.AlertRules) != 2 || github.AlertRules[0].Window != "medium" {
+		t.Error("Expected the website's thresholds to replace the default ones, got", github.AlertRules)
+	}
+	if len(github.CertExpiry) != 2 || github.CertExpiry[0] != 14 {
+		t.Error("Unexpected certificate expiry thresholds", github.CertExpiry)
+	}
+	if github.Retries != 2 || github.RetryDelay != 100*time.Millisecond || github.ConfirmAfter != 3 {
+		t.Error("Unexpected retries", github.Retries, github.RetryDelay, github.ConfirmAfter)
+	}
+	if len(github.Windows) != 2 || github.Windows[1].Duration != 10*time.Minute {
+		t.Error("Unexpected windows", github.Windows)
+	}
+	if len(github.Assertions.Status) != 2 || github.Assertions.Status[1].Min != 301 {
+		t.Error("Unexpected status assertions", github.Assertions.Status)
+	}
+	if github.Assertions.JSONPaths["data.status"] != "ok" || len(github.Assertions.Contains) != 1 {
+		t.Error("Unexpected body assertions", github.Assertions)
+	}
+
+	google := websites[1]
+	if google.Url != "http://www.google.com" || google.CheckInterval != 2000 {
+		t.Error("Expected the default interval and a http:// url, got", google.Url, google.CheckInterval)
+	}
+	if len(google.AlertRules) != 1 || google.Windows != nil {
+		t.Error("Expected the default thresholds and windows, got", google.AlertRules, google.Windows)
+	}
+}
+
+func TestStructured_JSON(t *testing.T) {
+	path := writeConfig(t, "suricata.json", jsonConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	websites, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(websites) != 2 {
+		t.Fatal("Expected 2 websites, got", len(websites))
+	}
+	if websites[0].Timeout != 800*time.Millisecond || websites[0].CheckInterval != 2000 {
+		t.Error("Unexpected timeout or interval", websites[0].Timeout, websites[0].CheckInterval)
+	}
+	if websites[1].Headers["Accept"] != "text/html" || len(websites[1].Tags) != 1 {
+		t.Error("Unexpected headers or tags", websites[1].Headers, websites[1].Tags)
+	}
+	if len(websites[1].AlertRules) != 0 {
+		t.Error("Expected no alert rules, got", websites[1].AlertRules)
+	}
+}
+
+func TestStructured_Errors(t *testing.T) {
+	configs := map[string]string{
+		"missing url":      "websites:\n  - name: github\n",
+		"unknown field":    "websites:\n  - url: github.com\n    retry: 3\n",
+		"invalid duration": "websites:\n  - url: github.com\n    interval: fast\n",
+		"sub-ms interval":  "websites:\n  - url: github.com\n    interval: 10us\n",
+		"invalid rule":     "websites:\n  - url: github.com\n    thresholds: [latency]\n",
+		"invalid status":   "websites:\n  - url: github.com\n    assertions:\n      status: [ok]\n",
+		"invalid regexp":   "websites:\n  - url: github.com\n    assertions:\n      matches: [\"(\"]\n",
+	}
+	for name, content := range configs {
+		_, err := parseStructured([]byte(content), FORMAT_YAML)
+		if err == nil {
+			t.Error("Expected an error for", name)
+		}
+	}
+	_, err := parseStructured([]byte(`{"websites": [{"url": "github.com", "interval": true}]}`), FORMAT_JSON)
+	if err == nil {
+		t.Error("Expected an error for a boolean interval")
+	}
+}
@@ -17,18 +17,23 @@ var (
 	once sync.Once
 )
 
-//Singleton
+// Singleton, see NewDisplay
 func GetDisplay() *Display {
 	once.Do(func() {
-		disp = &Display{
-			messages: newMsgHolder(),
-			info:     newInfoHolder(),
-			measures: newMeasures(),
-		}
+		disp = NewDisplay()
 	})
 	return disp
 }
 
+// Display independent from others, eg of another orchestrator
+func NewDisplay() *Display {
+	return &Display{
+		messages: newMsgHolder(),
+		info:     newInfoHolder(),
+		measures: newMeasures(),
+	}
+}
+
 // Update Messages component
 func (u *Display) UpdateMessages(messages []string) error {
 	msg := newMsgHolder()
@@ -51,7 +56,7 @@ func (u *Display) UpdateMeasures(websites []string, o *monitor.Orchestrator) err
 
 	rows := [][]string{
 		// Headers of the report table
-		{"website", "period", "average response", "max response time", "availability", "[2XX](fg-green)", "[5XX](fg-red)", "[4XX](fg-yellow)", "[Unsuccessful %](fg-magenta)"},
+		{"website", "period", "average response", "max response time", "p50", "p90", "p95", "p99", "availability", "[2XX](fg-green)", "[5XX](fg-red)", "[4XX](fg-yellow)", "[Unsuccessful %](fg-magenta)", "avg dns/tcp/tls/ttfb/xfer", "max dns/tcp/tls/ttfb/xfer"},
 	}
 
 	// Populate table with data from Summary
@@ -0,0 +1,190 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"suricata/monitor"
+)
+
+// Content type of the Prometheus text exposition format
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// A Prometheus metric family
+type family struct {
+	name    string
+	help    string
+	kind    string
+	samples []sample
+}
+
+type sample struct {
+	labels [][2]string
+	value  float64
+}
+
+// HTTP handler serving the metrics of all websites registered in o
+func Handler(o *monitor.Orchestrator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		err := WriteMetrics(w, o)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// Write the metrics of all websites registered in o, in Prometheus text format
+func WriteMetrics(w io.Writer, o *monitor.Orchestrator) error {
+	active := family{"suricata_monitoring_active", "Whether the website is being monitored.", "gauge", nil}
+	availability := family{"suricata_availability_ratio", "Share of available checks over the window.", "gauge", nil}
+	avgRes := family{"suricata_response_time_avg_milliseconds", "Average response time over the window.", "gauge", nil}
+	maxRes := family{"suricata_response_time_max_milliseconds", "Maximum response time over the window.", "gauge", nil}
+	statusShare := family{"suricata_status_class_ratio", "Share of responses per status class over the window.", "gauge", nil}
+	errors := family{"suricata_errors", "Number of unsuccessful requests over the window.", "gauge", nil}
+	failures := family{"suricata_failures", "Number of unsuccessful checks per cause over the window, assertion failures included.", "gauge", nil}
+	unconfirmed := family{"suricata_unconfirmed_failures", "Number of unsuccessful checks not confirmed yet over the window, counted as available.", "gauge", nil}
+	retries := family{"suricata_retries", "Number of attempts retried within checks over the window.", "gauge", nil}
+	checks := family{"suricata_checks_total", "Number of checks per status code since registration.", "counter", nil}
+	certExpiry := family{"suricata_certificate_expiry_days", "Days until the TLS certificate expires, as of the latest check.", "gauge", nil}
+	certValid := family{"suricata_certificate_valid", "Whether the TLS certificate chain is valid for the website.", "gauge", nil}
+
+	for _, url := range o.GetUrls() {
+		isActive, err := o.IsActive(url)
+		if err != nil {
+			// Unregistered in the meantime
+			continue
+		}
+		active.add(boolToFloat(isActive), [2]string{"url", url})
+
+		aggs, err := o.GetAggregator(url)
+		if err != nil {
+			continue
+		}
+		for idx, window := range aggs.Windows {
+			agg := aggs.Aggregators[idx]
+			labels := [][2]string{{"url", url}, {"window", window.Name}}
+			count, _ := agg.GetCount()
+			errCount, _ := agg.GetErrorCount()
+			errors.add(float64(errCount), labels...)
+			counts, _ := agg.GetFailureCounts()
+			for _, kind := range sortedKeys(counts) {
+				failures.add(float64(counts[kind]), append(labels, [2]string{"cause", kind})...)
+			}
+			unconfirmedCount, _ := agg.GetUnconfirmedCount()
+			unconfirmed.add(float64(unconfirmedCount), labels...)
+			retryCount, _ := agg.GetRetryCount()
+			retries.add(float64(retryCount), labels...)
+			if count+errCount == 0 {
+				continue
+			}
+			value, _ := agg.GetAvailability()
+			availability.add(fromFloat32(value), labels...)
+			if count == 0 {
+				continue
+			}
+			value, _ = agg.GetAvgResTime()
+			avgRes.add(fromFloat32(value), labels...)
+			value, _ = agg.GetMaxResTime()
+			if value >= 0 {
+				maxRes.add(fromFloat32(value), labels...)
+			}
+			// Status classes are meaningless for checks without status, eg TCP ones
+			responses, _ := agg.GetResponseCount()
+			if responses == 0 {
+				continue
+			}
+			for class := 1; class <= 5; class++ {
+				value, _ = agg.GetStatusAgg(class)
+				statusShare.add(fromFloat32(value), append(labels, [2]string{"class", strconv.Itoa(class) + "xx"})...)
+			}
+		}
+
+		counts := aggs.Checks.GetCounts()
+		statuses := make([]int, 0, len(counts))
+		for status := range counts {
+			statuses = append(statuses, status)
+		}
+		sort.Ints(statuses)
+		for _, status := range statuses {
+			code := strconv.Itoa(status)
+			switch status {
+			case 0:
+				code = "error"
+			case monitor.STATUS_NONE:
+				code = "none"
+			}
+			checks.add(float64(counts[status]), [2]string{"url", url}, [2]string{"code", code})
+		}
+
+		if cert := aggs.Certificates.Latest(); cert != nil {
+			certExpiry.add(float64(cert.DaysToExpiry), [2]string{"url", url})
+			certValid.add(boolToFloat(cert.ChainError == ""), [2]string{"url", url})
+		}
+	}
+
+	buf := bufio.NewWriter(w)
+	for _, f := range []family{active, availability, avgRes, maxRes, statusShare, errors, failures, unconfirmed, retries, checks, certExpiry, certValid} {
+		f.write(buf)
+	}
+	return buf.Flush()
+}
+
+func (f *family) add(value float64, labels ...[2]string) {
+	f.samples = append(f.samples, sample{labels, value})
+}
+
+func (f *family) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, f.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+	for _, s := range f.samples {
+		pairs := make([]string, len(s.labels))
+		for i, label := range s.labels {
+			pairs[i] = label[0] + `="` + escapeLabel(label[1]) + `"`
+		}
+		fmt.Fprintf(w, "%s{%s} %s\n", f.name, strings.Join(pairs, ","), formatValue(s.value))
+	}
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// Convert without float32 rounding artifacts (0.33333334 instead of 0.3333333432674408)
+func fromFloat32(value float32) float64 {
+	out, _ := strconv.ParseFloat(strconv.FormatFloat(float64(value), 'g', -1, 32), 64)
+	return out
+}
+
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
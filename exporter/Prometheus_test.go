@@ -0,0 +1,105 @@
+package exporter
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"suricata/monitor"
+	"testing"
+	"time"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	o, err := monitor.NewOrchestrator(monitor.OrchestratorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	url := "http://www.example.com"
+	err = o.Register(monitor.Website{Url: url, CheckInterval: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Unregister(url)
+
+	now := time.Now()
+	logs := []monitor.PingLog{
+		{Website: url, Time: now, Status: 200, ResponseTime: 50 * time.Millisecond, Retried: []string{monitor.FAILURE_TIMEOUT}},
+		{Website: url, Time: now.Add(time.Second), Status: 200, ResponseTime: 150 * time.Millisecond},
+		{Website: url, Time: now.Add(2 * time.Second), Status: 503, ResponseTime: 10 * time.Millisecond},
+		{Website: url, Time: now.Add(3 * time.Second), Error: errors.New("timeout")},
+		{Website: url, Time: now.Add(4 * time.Second), Error: errors.New("x509"), Certificate: &monitor.Certificate{DaysToExpiry: 12, ChainError: "x509"}},
+	}
+	for _, log := range logs {
+		err = o.AggLog(log)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A single successful check without status, as TCP ones
+	tcpUrl := "tcp://www.example.com:22"
+	err = o.Register(monitor.Website{Url: tcpUrl, CheckInterval: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Unregister(tcpUrl)
+	err = o.AggLog(monitor.PingLog{Website: tcpUrl, Time: now, ResponseTime: 20 * time.Millisecond, Asserted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = WriteMetrics(&buf, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+	t.Log(output)
+
+	expected := []string{
+		"# TYPE suricata_availability_ratio gauge",
+		`suricata_monitoring_active{url="http://www.example.com"} 0`,
+		`suricata_availability_ratio{url="http://www.example.com",window="short"} 0.4`,
+		`suricata_response_time_avg_milliseconds{url="http://www.example.com",window="medium"} 70`,
+		`suricata_response_time_max_milliseconds{url="http://www.example.com",window="long"} 150`,
+		`suricata_status_class_ratio{url="http://www.example.com",window="short",class="5xx"} 0.33333334`,
+		`suricata_errors{url="http://www.example.com",window="short"} 2`,
+		`suricata_failures{url="http://www.example.com",window="short",cause="other"} 2`,
+		`suricata_unconfirmed_failures{url="http://www.example.com",window="short"} 0`,
+		`suricata_retries{url="http://www.example.com",window="long"} 1`,
+		"# TYPE suricata_checks_total counter",
+		`suricata_checks_total{url="http://www.example.com",code="200"} 2`,
+		`suricata_checks_total{url="http://www.example.com",code="503"} 1`,
+		`suricata_checks_total{url="http://www.example.com",code="error"} 2`,
+		`suricata_certificate_expiry_days{url="http://www.example.com"} 12`,
+		`suricata_certificate_valid{url="http://www.example.com"} 0`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Error("Missing line:", line)
+		}
+	}
+
+	// Samples without a value are left out rather than written as NaN
+	unexpected := []string{
+		`suricata_status_class_ratio{url="tcp://www.example.com:22"`,
+		"NaN",
+	}
+	for _, line := range unexpected {
+		if strings.Contains(output, line) {
+			t.Error("Unexpected line:", line)
+		}
+	}
+	if !strings.Contains(output, `suricata_response_time_max_milliseconds{url="tcp://www.example.com:22",window="short"} 20`+"\n") {
+		t.Error("Missing response time of the TCP website")
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	actual := escapeLabel("http://example.com/\"q\"\\\n")
+	expected := `http://example.com/\"q\"\\\n`
+	if actual != expected {
+		t.Error("Actual:", actual, "Expected:", expected)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"suricata/journal"
+	"suricata/monitor"
+	"suricata/notifier"
+	"syscall"
+)
+
+// Run the orchestrator without the terminal UI, until SIGINT or SIGTERM is received
+// The config file is reloaded on SIGHUP and when modified
+// Alerts and reports are written to logFile, or to stdout if logFile is empty,
+// and appended to the journal if any. Down and recovered alerts are sent to webhook, if any
+func runHeadless(logFile string, j *journal.Journal, webhook *notifier.Webhook) error {
+	out := os.Stdout
+	if logFile != "" {
+		file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	logger := log.New(out, "", log.LstdFlags)
+	if webhook != nil {
+		webhook.OnError = func(err error) {
+			logError(logger, err)
+		}
+	}
+
+	pipeline := make(chan monitor.PingLog)
+	orchestrator, err := monitor.NewOrchestrator(monitor.OrchestratorOptions{
+		Pipeline:  pipeline,
+		Windows:   windows,
+		Scheduler: scheduler,
+	})
+	if err != nil {
+		return err
+	}
+	go orchestrator.Run(context.Background())
+	defer orchestrator.Close()
+	// Every alert is logged, slowing the orchestrator down rather than dropping them
+	alerts, err := orchestrator.Subscribe(monitor.SubscriptionOptions{
+		Overflow: monitor.OVERFLOW_BLOCK,
+		Types:    monitor.AlertEvents(),
+	})
+	if err != nil {
+		return err
+	}
+	err = serveHTTP(*httpAddr, orchestrator)
+	if err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	// Registering and stopping publish alerts: run them aside the alert loop
+	launched := make(chan bool)
+	go func() {
+		launch(orchestrator, websites)
+		orchestrator.StartAll()
+		close(launched)
+	}()
+	stopped := make(chan bool)
+	stopping := false
+
+	reloads, stopWatching := watchConfig(orchestrator, *configFile, websites, *reloadInterval, launched)
+	refreshes, stopRefresh := refreshTickers(refreshIntervals(websites))
+	defer func() { stopRefresh() }()
+
+	for {
+		select {
+		// Log data of the windows due for a refresh
+		case refresh := <-refreshes:
+			if stopping {
+				continue
+			}
+			updated := updateReports(orchestrator, refresh)
+			logReports(logger, updated)
+			logError(logger, writeMeasures(j, updated))
+
+		case event := <-alerts.Events():
+			logAlert(logger, j, webhook, *event.Alert)
+
+		// Windows of the reloaded websites may need other refresh intervals
+		case reload := <-reloads:
+			logger.Println("CONFIG", reload)
+			if reload.err == nil {
+				websites = reload.websites
+				stopRefresh()
+				refreshes, stopRefresh = refreshTickers(refreshIntervals(websites))
+			}
+
+		case sig := <-signals:
+			if stopping {
+				continue
+			}
+			logger.Println("Received", sig, "- shutting down")
+			stopping = true
+			go func() {
+				<-launched
+				stopWatching()
+				stop(orchestrator)
+				close(stopped)
+			}()
+
+		case <-stopped:
+			// Log the alerts published while stopping
+			alerts.Close()
+			for event := range alerts.Events() {
+				logAlert(logger, j, webhook, *event.Alert)
+			}
+			if webhook != nil {
+				webhook.Wait()
+			}
+			logger.Println("Stopped")
+			return nil
+		}
+	}
+}
+
+func logAlert(logger *log.Logger, j *journal.Journal, webhook *notifier.Webhook, alert monitor.Alert) {
+	logger.Println("ALERT", alert.PlainMessage())
+	if j != nil {
+		logError(logger, j.WriteAlert(alert))
+	}
+	if webhook != nil {
+		webhook.Notify(alert)
+	}
+}
+
+func logReports(logger *log.Logger, updated []siteMeasures) {
+	for _, site := range updated {
+		logger.Println("REPORT", site.url, site.measures)
+	}
+}
+
+func logError(logger *log.Logger, err error) {
+	if err != nil {
+		logger.Println("ERROR", err)
+	}
+}
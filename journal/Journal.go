@@ -0,0 +1,201 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"suricata/monitor"
+	"sync"
+	"time"
+)
+
+const filePrefix = "suricata-"
+const fileSuffix = ".jsonl"
+const fileTimeLayout = "20060102T150405.000"
+
+// Journal appends alerts and measures as JSON lines to files of a directory
+// The current file is rotated when it grows over MaxSize or gets older than RotateEvery,
+// and rotated files older than Retention are deleted
+type Journal struct {
+	Dir         string
+	MaxSize     int64         // Maximum size of a file in bytes, unlimited if 0
+	RotateEvery time.Duration // Maximum age of a file, unlimited if 0
+	Retention   time.Duration // Rotated files are deleted this long after their last write, kept forever if 0
+	file        *os.File
+	size        int64
+	openedAt    time.Time
+	now         func() time.Time
+	mutex       sync.Mutex
+}
+
+// A line of the journal
+type Record struct {
+	Type      string            `json:"type"` // "alert" or "measures"
+	Timestamp time.Time         `json:"timestamp"`
+	Url       string            `json:"url"`
+	Message   string            `json:"message,omitempty"`
+	Kind      string            `json:"kind,omitempty"`   // Kind of the alert, "info", "down" or "up"
+	Metric    string            `json:"metric,omitempty"` // Metric of the rule raising the alert, if any
+	Value     float32           `json:"value"`            // Value of the metric, 0 included
+	Measures  *monitor.Measures `json:"measures,omitempty"`
+}
+
+func NewJournal(dir string, maxSize int64, rotateEvery time.Duration, retention time.Duration) (*Journal, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{
+		Dir:         dir,
+		MaxSize:     maxSize,
+		RotateEvery: rotateEvery,
+		Retention:   retention,
+		now:         time.Now,
+	}, nil
+}
+
+// Append an alert
+func (j *Journal) WriteAlert(alert monitor.Alert) error {
+	return j.write(Record{
+		Type:      "alert",
+		Timestamp: alert.Timestamp,
+		Url:       alert.Url,
+		Message:   alert.PlainMessage(),
+		Kind:      alert.Kind.String(),
+		Metric:    alert.Metric,
+		Value:     alert.Value,
+	})
+}
+
+// Append a snapshot of the measures of url
+func (j *Journal) WriteMeasures(url string, measures monitor.Measures) error {
+	return j.write(Record{
+		Type:      "measures",
+		Timestamp: j.now(),
+		Url:       url,
+		Measures:  &measures,
+	})
+}
+
+// Close the current file
+func (j *Journal) Close() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+func (j *Journal) write(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.file == nil || j.shouldRotate(int64(len(line))) {
+		err = j.rotate()
+		if err != nil {
+			return err
+		}
+	}
+	n, err := j.file.Write(line)
+	j.size += int64(n)
+	return err
+}
+
+// Whether writing n more bytes requires a new file
+func (j *Journal) shouldRotate(n int64) bool {
+	if j.MaxSize > 0 && j.size > 0 && j.size+n > j.MaxSize {
+		return true
+	}
+	if j.RotateEvery > 0 && j.now().Sub(j.openedAt) >= j.RotateEvery {
+		return true
+	}
+	return false
+}
+
+// Close the current file, open a new one and apply retention
+func (j *Journal) rotate() error {
+	if j.file != nil {
+		err := j.file.Close()
+		j.file = nil
+		if err != nil {
+			return err
+		}
+	}
+	now := j.now()
+	name := filepath.Join(j.Dir, filePrefix+now.Format(fileTimeLayout)+fileSuffix)
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	j.file = file
+	j.size = info.Size()
+	j.openedAt = now
+	return j.prune(filepath.Base(name))
+}
+
+// Delete journal files last written before Retention, except current
+func (j *Journal) prune(current string) error {
+	if j.Retention <= 0 {
+		return nil
+	}
+	files, err := j.Files()
+	if err != nil {
+		return err
+	}
+	limit := j.now().Add(-j.Retention)
+	var errs []string
+	for _, name := range files {
+		if name == current {
+			continue
+		}
+		path := filepath.Join(j.Dir, name)
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Before(limit) {
+			continue
+		}
+		err = os.Remove(path)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New("FAILED TO DELETE OLD JOURNAL FILES: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Names of the journal files in Dir, oldest first
+func (j *Journal) Files() ([]string, error) {
+	entries, err := ioutil.ReadDir(j.Dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileSuffix) {
+			files = append(files, name)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
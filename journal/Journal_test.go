@@ -0,0 +1,156 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"suricata/monitor"
+	"testing"
+	"time"
+)
+
+// Journal in a temporary directory, with a controllable clock
+func newTestJournal(t *testing.T, maxSize int64, rotateEvery time.Duration, retention time.Duration) (*Journal, *time.Time) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := NewJournal(dir, maxSize, rotateEvery, retention)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Date(2018, 11, 11, 11, 10, 0, 0, time.Local)
+	j.now = func() time.Time { return clock }
+	return j, &clock
+}
+
+func readRecords(t *testing.T, path string) []Record {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records := make([]Record, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			t.Fatal("Invalid JSON line:", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestJournal_Write(t *testing.T) {
+	j, _ := newTestJournal(t, 0, 0, 0)
+	defer os.RemoveAll(j.Dir)
+	defer j.Close()
+
+	err := j.WriteAlert(monitor.Alert{
+		Url:       "http://www.example.com",
+		Timestamp: time.Now(),
+		Value:     0.5,
+		Message:   "[Website http://www.example.com is down !](fg-red)",
+		Init:      true,
+		Kind:      monitor.ALERT_DOWN,
+		Metric:    monitor.METRIC_AVAILABILITY,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = j.WriteAlert(monitor.Alert{
+		Url:       "http://www.example.com",
+		Timestamp: time.Now(),
+		Value:     0,
+		Message:   "Certificate of http://www.example.com has expired",
+		Kind:      monitor.ALERT_DOWN,
+		Metric:    monitor.METRIC_CERT_EXPIRY,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = j.WriteMeasures("http://www.example.com", monitor.Measures{Period: "Past 10 min", Availability: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := j.Files()
+	if len(files) != 1 {
+		t.Fatal("Expected 1 journal file, got", files)
+	}
+	records := readRecords(t, filepath.Join(j.Dir, files[0]))
+	if len(records) != 3 {
+		t.Fatal("Expected 3 records, got", len(records))
+	}
+	if records[0].Type != "alert" || records[0].Message != "Website http://www.example.com is down !" {
+		t.Error("Alert record does not match expectation:", records[0])
+	}
+	if records[0].Kind != "down" || records[0].Metric != monitor.METRIC_AVAILABILITY || records[0].Value != 0.5 {
+		t.Error("Alert record does not carry the kind, metric and value of the alert:", records[0])
+	}
+	if records[2].Type != "measures" || records[2].Measures == nil || records[2].Measures.Availability != 0.5 {
+		t.Error("Measures record does not match expectation:", records[2])
+	}
+
+	// A value of 0 is written, not mistaken for a missing one
+	content, _ := ioutil.ReadFile(filepath.Join(j.Dir, files[0]))
+	lines := strings.Split(string(content), "\n")
+	if !strings.Contains(lines[1], `"value":0`) || !strings.Contains(lines[1], `"metric":"cert_expiry"`) {
+		t.Error("Expected the value of the alert to be written, got", lines[1])
+	}
+}
+
+func TestJournal_RotateOnSize(t *testing.T) {
+	j, clock := newTestJournal(t, 200, 0, 0)
+	defer os.RemoveAll(j.Dir)
+	defer j.Close()
+
+	for i := 0; i < 5; i++ {
+		*clock = clock.Add(time.Second)
+		err := j.WriteAlert(monitor.Alert{Url: "http://www.example.com", Message: "Website http://www.example.com is up again !"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, _ := j.Files()
+	if len(files) < 2 {
+		t.Error("Journal was not rotated on size:", files)
+	}
+	for _, name := range files {
+		info, _ := os.Stat(filepath.Join(j.Dir, name))
+		if info.Size() > 200 {
+			t.Error("File", name, "exceeds max size:", info.Size())
+		}
+	}
+}
+
+func TestJournal_RotateOnTimeAndRetention(t *testing.T) {
+	j, clock := newTestJournal(t, 0, time.Hour, 2*time.Hour)
+	defer os.RemoveAll(j.Dir)
+	defer j.Close()
+
+	alert := monitor.Alert{Url: "http://www.example.com", Message: "Website http://www.example.com is down !"}
+	j.WriteAlert(alert)
+	*clock = clock.Add(30 * time.Minute)
+	j.WriteAlert(alert)
+	files, _ := j.Files()
+	if len(files) != 1 {
+		t.Fatal("Journal should not have been rotated yet:", files)
+	}
+	first := files[0]
+	// Pretend the first file was last written 3 hours ago
+	old := time.Now().Add(-3 * time.Hour)
+	os.Chtimes(filepath.Join(j.Dir, first), old, old)
+
+	*clock = time.Now()
+	j.WriteAlert(alert)
+	files, _ = j.Files()
+	if len(files) != 1 || files[0] == first {
+		t.Error("Journal was not rotated, or old file was not deleted:", files)
+	}
+}
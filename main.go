@@ -1,49 +1,130 @@
 package main
 
 import (
-	"bufio"
-	"errors"
+	"context"
 	"flag"
 	"fmt"
 	ui "github.com/gizak/termui"
 	"log"
-	"os"
-	"strconv"
+	"net"
+	"net/http"
 	"strings"
+	"suricata/api"
+	"suricata/config"
 	"suricata/cui"
+	"suricata/exporter"
+	"suricata/journal"
 	"suricata/monitor"
+	"suricata/notifier"
+	"sync"
 	"time"
 )
 
-//
-const DEFAULT_CHECKING_INTERVAL int = 1000
-const REFRESH_INTERVAL_MEDIUM = time.Second * 10
-const REFRESH_INTERVAL_LONG = time.Minute
-
 // Info to display to the user
 var info = []string{
 	"press q to quit",
 	"press s to resume monitoring",
 	"press p to pause monitoring",
-	"availability: % of status code 200",
-	"Unsuccessful requests can have timed out",
+	"availability: % of checks passing assertions (200 by default)",
+	"alerts: availability <= 80% over the first window, unless configured",
+	"unsuccessful: failed requests, with failures by cause",
+	"(dns, refused, reset, timeout, tls, redirects, assertion, other)",
 }
 
 // Loads ./config.sample by default
-var configFile = flag.String("cfg", "./config.sample", "Config file containing the websites to monitor and the check inbtervals")
+var configFile = flag.String("cfg", "./config.sample", "Config file containing the websites to monitor and the check inbtervals, as CSV lines, YAML (.yaml, .yml) or JSON (.json)")
+
+// Interval at which the config file is checked for modifications, it is also reloaded on SIGHUP
+var reloadInterval = flag.Duration("reload-interval", 2*time.Second, "Interval at which the config file is checked for modifications, 0 to reload it on SIGHUP only")
+
+// Run without the terminal UI
+var headless = flag.Bool("headless", false, "Run without the terminal UI, writing alerts and reports to stdout or to the log file")
+var logFile = flag.String("log", "", "File to write alerts and reports to in headless mode, stdout by default")
+
+// Persistent journal of alerts and measures, disabled if no directory is given
+var journalDir = flag.String("journal", "", "Directory to append alerts and measures to, as JSON lines")
+var journalMaxSize = flag.Int64("journal-max-size", 10, "Size in MB after which the journal file is rotated, 0 for no limit")
+var journalRotate = flag.Duration("journal-rotate", 24*time.Hour, "Age after which the journal file is rotated, 0 for no limit")
+var journalRetention = flag.Duration("journal-retention", 30*24*time.Hour, "Age after which rotated journal files are deleted, 0 to keep them")
+
+// HTTP server exposing metrics and the API, disabled if no address is given
+var httpAddr = flag.String("http", "", "Address of the HTTP server exposing Prometheus metrics on /metrics and the JSON API on /api/, eg :9090")
+
+// Webhooks notified of down and recovered alerts
+var webhooks = flag.String("webhooks", "", "Comma-separated urls to POST down and recovered alerts to")
+var webhookTimeout = flag.Duration("webhook-timeout", 5*time.Second, "Timeout of a webhook request")
+var webhookRetries = flag.Int("webhook-retries", 3, "Number of retries of a failed webhook request")
+
+// Aggregation windows of websites not defining theirs
+var windowsFlag = flag.String("windows", "", "Comma-separated aggregation windows, as <duration> or <name>=<duration>, eg 1m,5m,daily=24h. Defaults to short=2m,medium=10m,long=1h")
+
+// Scheduling of the checks
+var maxConcurrency = flag.Int("max-concurrency", monitor.DEFAULT_MAX_CONCURRENCY, "Checks running at once, 0 to run the checks of each website in its own goroutine")
+var hostConcurrency = flag.Int("host-concurrency", 0, "Checks running at once per host, 0 for no limit")
+var jitter = flag.Duration("jitter", time.Second, "Maximum random delay of the first check of each website, capped to its check interval")
 
 var websites []monitor.Website
-var urls []string
+var windows []monitor.Window
+var scheduler *monitor.Scheduler // Runs the checks, nil if each website runs its own
+
+// Measures of a website over a window
+type siteMeasures struct {
+	url      string
+	measures monitor.Measures
+}
 
 func main() {
 	flag.Parse()
 
-	w, u, err := parseConfig(*configFile)
+	windows = monitor.DefaultWindows()
+	if *windowsFlag != "" {
+		parsed, err := monitor.ParseWindows(strings.Split(*windowsFlag, ","))
+		if err != nil {
+			log.Fatal(err)
+		}
+		windows = parsed
+	}
+
+	var err error
+	websites, err = config.Load(*configFile)
 	if err != nil {
-		panic("Failed to read config file !")
+		log.Fatal("Failed to read config file: ", err)
+	}
+
+	if *maxConcurrency > 0 {
+		scheduler, err = monitor.NewScheduler(monitor.SchedulerOptions{
+			MaxConcurrency:  *maxConcurrency,
+			HostConcurrency: *hostConcurrency,
+			Jitter:          *jitter,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		scheduler.Start()
+		defer scheduler.Stop()
+	}
+
+	var j *journal.Journal
+	if *journalDir != "" {
+		j, err = journal.NewJournal(*journalDir, *journalMaxSize*1024*1024, *journalRotate, *journalRetention)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer j.Close()
+	}
+
+	var webhook *notifier.Webhook
+	if *webhooks != "" {
+		webhook = notifier.NewWebhook(strings.Split(*webhooks, ","), *webhookTimeout, *webhookRetries)
+	}
+
+	if *headless {
+		err = runHeadless(*logFile, j, webhook)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
-	websites = w
-	urls = u
 
 	err = ui.Init()
 	if err != nil {
@@ -52,39 +133,122 @@ func main() {
 	defer ui.Close()
 
 	pipeline := make(chan monitor.PingLog)
-	alerts := make(chan monitor.Alert)
 	messages := make([]string, 0)
+	// Errors of the journal and webhooks, dropped if the display lags behind
+	notices := make(chan string, 8)
+	notice := func(message string) {
+		select {
+		case notices <- message:
+		default:
+		}
+	}
+
+	orchestrator, err := monitor.NewOrchestrator(monitor.OrchestratorOptions{
+		Pipeline:  pipeline,
+		Windows:   windows,
+		Scheduler: scheduler,
+	})
+	if err != nil {
+		ui.Close()
+		log.Fatal(err)
+	}
+	go orchestrator.Run(context.Background())
+	if webhook != nil {
+		// Once the websites are stopped and the consumers done, as in headless mode
+		defer webhook.Wait()
+	}
+	// Closing the orchestrator closes the subscriptions, consumers then handle the alerts left
+	var consumers sync.WaitGroup
+	defer consumers.Wait()
+	defer orchestrator.Close()
+	display := cui.NewDisplay()
+
+	// The display only shows the latest alerts, the journal and webhooks get them all
+	alerts := subscribeAlerts(orchestrator, monitor.OVERFLOW_DROP_OLDEST, monitor.AlertEvents())
+	if j != nil {
+		subscription := subscribeAlerts(orchestrator, monitor.OVERFLOW_BLOCK, monitor.AlertEvents())
+		consumers.Add(1)
+		go func() {
+			defer consumers.Done()
+			journalAlerts(j, subscription, func(err error) {
+				notice("[Failed to write journal: " + err.Error() + "](fg-red)")
+			})
+		}()
+	}
+	if webhook != nil {
+		webhook.OnError = func(err error) {
+			notice("[" + err.Error() + "](fg-red)")
+		}
+		subscription := subscribeAlerts(orchestrator, monitor.OVERFLOW_BLOCK, []monitor.EventType{monitor.EVENT_THRESHOLD, monitor.EVENT_RECOVERED})
+		consumers.Add(1)
+		go func() {
+			defer consumers.Done()
+			notifyAlerts(webhook, subscription)
+		}()
+	}
+
+	err = serveHTTP(*httpAddr, orchestrator)
+	if err != nil {
+		ui.Close()
+		log.Fatal(err)
+	}
 
-	orchestrator := monitor.GetOrchestrator(pipeline, alerts)
-	display := cui.GetDisplay()
+	launched := make(chan bool)
+	reloads, stopWatching := watchConfig(orchestrator, *configFile, websites, *reloadInterval, launched)
+	defer stopWatching()
 
 	go func(display *cui.Display) {
 		stopTick := time.NewTimer(30 * time.Minute)
-		mediumTick := time.NewTicker(REFRESH_INTERVAL_MEDIUM)
-		longTick := time.NewTicker(REFRESH_INTERVAL_LONG)
+		refreshes, stopRefresh := refreshTickers(refreshIntervals(websites))
+		defer func() { stopRefresh() }()
+		events := alerts.Events()
 		loop := true
 
 		for loop {
 			select {
-			// Every 10s, update medium term data
-			case <-mediumTick.C:
-				updateMedium(orchestrator)
-				display.UpdateMeasures(urls, orchestrator)
+			// Update data of the windows due for a refresh
+			case refresh := <-refreshes:
+				updated := updateReports(orchestrator, refresh)
+				writeMeasures(j, updated)
+				display.UpdateMeasures(orchestrator.GetUrls(), orchestrator)
+				render(display)
+
+			case event, ok := <-events:
+				if !ok {
+					// Closed with the orchestrator
+					events = nil
+					continue
+				}
+				messages = append(messages, event.Alert.Message)
+				if len(messages) > 8 {
+					messages = messages[len(messages)-8:]
+				}
+				display.UpdateMessages(messages)
 				render(display)
 
-				// Every 1mn, update long term data
-			case <-longTick.C:
-				updateLong(orchestrator)
-				display.UpdateMeasures(urls, orchestrator)
+			case message := <-notices:
+				messages = append(messages, message)
+				if len(messages) > 8 {
+					messages = messages[len(messages)-8:]
+				}
+				display.UpdateMessages(messages)
 				render(display)
 
-			case alert := <-alerts:
-				// TODO - write message in log file
-				messages = append(messages, alert.Message)
+			// Windows of the reloaded websites may need other refresh intervals
+			case reload := <-reloads:
+				if reload.err != nil {
+					messages = append(messages, "["+reload.String()+"](fg-red)")
+				} else {
+					websites = reload.websites
+					stopRefresh()
+					refreshes, stopRefresh = refreshTickers(refreshIntervals(websites))
+					messages = append(messages, reload.String())
+				}
 				if len(messages) > 8 {
 					messages = messages[len(messages)-8:]
 				}
 				display.UpdateMessages(messages)
+				display.UpdateMeasures(orchestrator.GetUrls(), orchestrator)
 				render(display)
 
 			case <-stopTick.C:
@@ -98,10 +262,11 @@ func main() {
 	defer stop(orchestrator)
 
 	orchestrator.StartAll()
+	close(launched)
 
 	display.UpdateInfo(info)
 	display.UpdateMessages(messages)
-	display.UpdateMeasures(urls, orchestrator)
+	display.UpdateMeasures(orchestrator.GetUrls(), orchestrator)
 	render(display)
 
 	// Launch orchestrator and register websites
@@ -111,13 +276,13 @@ func main() {
 
 	ui.Handle("s", func(ui.Event) {
 		orchestrator.StartAll()
-		display.UpdateMeasures(urls, orchestrator)
+		display.UpdateMeasures(orchestrator.GetUrls(), orchestrator)
 		render(display)
 	})
 
 	ui.Handle("p", func(ui.Event) {
 		orchestrator.PauseAll()
-		display.UpdateMeasures(urls, orchestrator)
+		display.UpdateMeasures(orchestrator.GetUrls(), orchestrator)
 		render(display)
 	})
 
@@ -132,6 +297,33 @@ func main() {
 	ui.Loop()
 }
 
+// Subscribe to the events of types of orchestrator, which are all alert events
+func subscribeAlerts(orchestrator *monitor.Orchestrator, overflow monitor.OverflowPolicy, types []monitor.EventType) *monitor.Subscription {
+	subscription, err := orchestrator.Subscribe(monitor.SubscriptionOptions{Overflow: overflow, Types: types})
+	if err != nil {
+		ui.Close()
+		log.Fatal(err)
+	}
+	return subscription
+}
+
+// Append the alerts of subscription to the journal, until it is closed
+func journalAlerts(j *journal.Journal, subscription *monitor.Subscription, onError func(error)) {
+	for event := range subscription.Events() {
+		err := j.WriteAlert(*event.Alert)
+		if err != nil {
+			onError(err)
+		}
+	}
+}
+
+// Send the alerts of subscription to the webhooks, until it is closed
+func notifyAlerts(webhook *notifier.Webhook, subscription *monitor.Subscription) {
+	for event := range subscription.Events() {
+		webhook.Notify(*event.Alert)
+	}
+}
+
 func launch(orchestrator *monitor.Orchestrator, websites []monitor.Website) {
 	for _, website := range websites {
 		err := orchestrator.Register(website)
@@ -141,30 +333,112 @@ func launch(orchestrator *monitor.Orchestrator, websites []monitor.Website) {
 	}
 }
 
+// Pause and unregister every website, errors are logged as the process is exiting anyway
 func stop(orchestrator *monitor.Orchestrator) {
 	err := orchestrator.PauseAll()
 	if err != nil {
-		panic(err)
+		log.Println("Failed to pause websites:", err)
 	}
 	err = orchestrator.UnregisterAll()
 	if err != nil {
-		panic(err)
+		log.Println("Failed to unregister websites:", err)
 	}
 }
 
-func updateMedium(orchestrator *monitor.Orchestrator) error {
+// Report refresh intervals of the windows of websites, and of the default windows
+func refreshIntervals(websites []monitor.Website) []time.Duration {
+	found := make(map[time.Duration]bool)
+	intervals := make([]time.Duration, 0)
+	all := append([]monitor.Window{}, windows...)
 	for _, website := range websites {
-		err := orchestrator.UpdateMediumReport(website.Url)
+		all = append(all, website.Windows...)
+	}
+	for _, window := range all {
+		if !found[window.Refresh()] {
+			found[window.Refresh()] = true
+			intervals = append(intervals, window.Refresh())
+		}
+	}
+	return intervals
+}
+
+// Send each interval on the returned channel every interval, until stop is called
+func refreshTickers(intervals []time.Duration) (<-chan time.Duration, func()) {
+	refreshes := make(chan time.Duration)
+	done := make(chan bool)
+	for _, interval := range intervals {
+		go func(interval time.Duration) {
+			tick := time.NewTicker(interval)
+			defer tick.Stop()
+			for {
+				select {
+				case <-tick.C:
+					select {
+					case refreshes <- interval:
+					case <-done:
+						return
+					}
+				case <-done:
+					return
+				}
+			}
+		}(interval)
+	}
+	return refreshes, func() { close(done) }
+}
+
+// Update reports over the windows refreshed every refresh, and return the updated measures
+func updateReports(orchestrator *monitor.Orchestrator, refresh time.Duration) []siteMeasures {
+	updated := make([]siteMeasures, 0)
+	for _, url := range orchestrator.GetUrls() {
+		siteWindows, err := orchestrator.GetWindows(url)
 		if err != nil {
-			return err
+			// Unregistered in the meantime
+			continue
+		}
+		for _, window := range siteWindows {
+			if window.Refresh() != refresh {
+				continue
+			}
+			err = orchestrator.UpdateReport(url, window.Name)
+			if err != nil {
+				continue
+			}
+			report := orchestrator.GetReport(url)
+			if report == nil {
+				continue
+			}
+			if measures := report.Get(window.Name); measures != nil {
+				updated = append(updated, siteMeasures{url, *measures})
+			}
 		}
 	}
+	return updated
+}
+
+// Serve metrics and the API over HTTP on addr, in the background
+func serveHTTP(addr string, orchestrator *monitor.Orchestrator) error {
+	if addr == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exporter.Handler(orchestrator))
+	mux.Handle("/api/", api.Handler(orchestrator))
+	go http.Serve(listener, mux)
 	return nil
 }
 
-func updateLong(orchestrator *monitor.Orchestrator) error {
-	for _, website := range websites {
-		err := orchestrator.UpdateLongReport(website.Url)
+// Append updated measures to the journal, if any
+func writeMeasures(j *journal.Journal, updated []siteMeasures) error {
+	if j == nil {
+		return nil
+	}
+	for _, site := range updated {
+		err := j.WriteMeasures(site.url, site.measures)
 		if err != nil {
 			return err
 		}
@@ -180,54 +454,3 @@ func render(display *cui.Display) error {
 	ui.Render(ui.Body)
 	return err
 }
-
-func parseConfig(fileLocation string) ([]monitor.Website, []string, error) {
-	file, err := os.Open(fileLocation)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer file.Close()
-
-	websites := make([]monitor.Website, 0)
-	urls := make([]string, 0)
-	foundUrls := make(map[string]bool)
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		var url string
-		var interval int
-
-		line := scanner.Text()
-		params := strings.Split(line, ",")
-		if len(params) > 2 {
-			return nil, nil, errors.New("INVALID CONFIG FILE: 1 WEBSITE PER lINE")
-		}
-
-		if !(strings.Index(params[0], "http://") == 0 || strings.Index(params[0], "https://") == 0) {
-			url = "http://" + params[0]
-		} else {
-			url = params[0]
-		}
-
-		if len(params) == 2 {
-			interval, err = strconv.Atoi(params[1])
-			if err != nil {
-				return nil, nil, errors.New("INVALID CONFIG FILE: INTERVAL MUST BE INTEGER")
-			}
-		} else {
-			interval = DEFAULT_CHECKING_INTERVAL
-		}
-
-		website := monitor.Website{Url: url, CheckInterval: interval}
-		if _, ok := foundUrls[url]; !ok {
-			websites = append(websites, website)
-			urls = append(urls, url)
-			foundUrls[url] = true
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, nil, err
-	}
-	return websites, urls, nil
-}
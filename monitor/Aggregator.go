@@ -1,6 +1,7 @@
 package monitor
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"sync"
@@ -12,24 +13,51 @@ const MEDIUM_INTERVAL = 10 * time.Minute
 const LONG_INTERAVL = time.Hour
 
 type Aggregator struct {
-	duration    time.Duration
-	website     string
-	last        *QueueElement
-	first       *QueueElement
-	heap        MaxHeap
-	count       int
-	errorCount  int
-	sumResTime  int64 // TODO - change this (less than 64bits is needed)
-	statusCount map[int]int
-	statusAgg   map[int]int
-	AlertStatus bool
-	mutex       sync.Mutex
+	duration       time.Duration
+	website        string
+	last           *QueueElement
+	first          *QueueElement
+	heap           MaxHeap
+	resTimes       SortedList
+	count          int
+	errorCount     int
+	availableCount int
+	assertionCount int
+	unconfirmed    int        // Unsuccessful checks not confirmed yet, counted as available
+	unconfirmedErr int        // Unconfirmed checks which failed with an error
+	unconfirmedRes int        // Unconfirmed checks with a status, eg 5xx responses
+	unconfirmed5xx int        // Unconfirmed checks with a 5xx status
+	unconfirmedSum int64      // Sum of the response times of unconfirmed checks without error
+	confirmedTimes SortedList // Response times of confirmed checks, errors included as in heap
+	confirmedRes   SortedList // Response times of confirmed checks without error, as in resTimes
+	retryCount     int        // Attempts retried within checks
+	sumResTime     int64      // TODO - change this (less than 64bits is needed)
+	sumPhases      Phases     // Sum of the phases of successful requests
+	statusCount    map[int]int
+	statusAgg      map[int]int
+	failureCount   map[string]int // Per category of failure
+	rules          []AlertRule
+	ruleStatus     []bool
+	AlertStatus    bool
+	mutex          sync.Mutex
 }
 
+// Aggregators of a website, one per window
 type Aggregators struct {
-	Short  *Aggregator // 2 min
-	Medium *Aggregator // 10 min
-	Long   *Aggregator // 1 hour
+	Windows      []Window
+	Aggregators  []*Aggregator // In the same order as Windows
+	Checks       *CheckCounter
+	Certificates *CertificateWatch
+}
+
+// Status of successful checks of non-HTTP websites, in CheckCounter
+const STATUS_NONE = -1
+
+// Cumulative count of checks per status code since registration,
+// 0 standing for errors and STATUS_NONE for successful checks without status, eg TCP ones
+type CheckCounter struct {
+	counts map[int]uint64
+	mutex  sync.Mutex
 }
 
 type QueueElement struct {
@@ -39,52 +67,80 @@ type QueueElement struct {
 	heapIndex int
 }
 
+// Aggregators on the default windows, with the default alert rules
 func NewAggregators(website string) Aggregators {
-	short := Aggregator{
-		duration:    SHORT_INTERVAL,
-		website:     website,
-		last:        nil,
-		first:       nil,
-		statusCount: make(map[int]int),
-		statusAgg:   make(map[int]int),
-		AlertStatus: false,
-		heap:        NewMaxHeap(),
-	}
-	medium := Aggregator{
-		duration:    MEDIUM_INTERVAL,
-		website:     website,
-		last:        nil,
-		first:       nil,
-		statusCount: make(map[int]int),
-		statusAgg:   make(map[int]int),
-		AlertStatus: false,
-		heap:        NewMaxHeap(),
-	}
-	long := Aggregator{
-		duration:    LONG_INTERAVL,
-		website:     website,
-		last:        nil,
-		first:       nil,
-		statusCount: make(map[int]int),
-		statusAgg:   make(map[int]int),
-		AlertStatus: false,
-		heap:        NewMaxHeap(),
+	return NewAggregatorsWithRules(website, DefaultWindows(), DefaultAlertRules())
+}
+
+// Aggregators on windows, evaluating each rule on its window
+// Rules without window are evaluated on the first one
+func NewAggregatorsWithRules(website string, windows []Window, rules []AlertRule) Aggregators {
+	aggregators := make([]*Aggregator, len(windows))
+	for idx, window := range windows {
+		aggregators[idx] = newAggregator(website, window.Duration, rulesFor(rules, window.Name, idx == 0))
 	}
 	return Aggregators{
-		Short:  &short,
-		Medium: &medium,
-		Long:   &long,
+		Windows:      windows,
+		Aggregators:  aggregators,
+		Checks:       &CheckCounter{counts: make(map[int]uint64)},
+		Certificates: NewCertificateWatch(DefaultCertExpiryDays()),
+	}
+}
+
+// Get the aggregator of window, nil if there is none
+func (a *Aggregators) Get(window string) *Aggregator {
+	for idx, w := range a.Windows {
+		if w.Name == window {
+			return a.Aggregators[idx]
+		}
+	}
+	return nil
+}
+
+func newAggregator(website string, duration time.Duration, rules []AlertRule) *Aggregator {
+	return &Aggregator{
+		duration:       duration,
+		website:        website,
+		last:           nil,
+		first:          nil,
+		statusCount:    make(map[int]int),
+		statusAgg:      make(map[int]int),
+		failureCount:   make(map[string]int),
+		rules:          rules,
+		ruleStatus:     make([]bool, len(rules)),
+		AlertStatus:    false,
+		heap:           NewMaxHeap(),
+		resTimes:       NewSortedList(),
+		confirmedTimes: NewSortedList(),
+		confirmedRes:   NewSortedList(),
+	}
+}
+
+// Rules evaluated on window
+func rulesFor(rules []AlertRule, window string, first bool) []AlertRule {
+	out := make([]AlertRule, 0)
+	for _, rule := range rules {
+		if rule.Window == window || (rule.Window == "" && first) {
+			out = append(out, rule)
+		}
 	}
+	return out
 }
 
-// Aggregate PingLog and update metrics
+// Aggregate PingLog, update metrics and return the first alert raised, if any
 func (a *Aggregator) Add(e QueueElement) (error, Alert) {
+	alerts, err := a.Aggregate(e)
+	if err != nil || len(alerts) == 0 {
+		return err, Alert{}
+	}
+	return nil, alerts[0]
+}
+
+// Aggregate PingLog, update metrics and return alerts raised by the rules of the aggregator
+func (a *Aggregator) Aggregate(e QueueElement) ([]Alert, error) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
-	// Eventual alert
-	var alert Alert
-
 	// Add e to queue
 	if a.first != nil {
 		a.first.next = &e
@@ -97,11 +153,44 @@ func (a *Aggregator) Add(e QueueElement) (error, Alert) {
 	a.heap.insert(&e)
 
 	// Update metrics
+	if a.first.Value.IsUp() {
+		a.availableCount++
+	}
+	if a.first.Value.Unconfirmed {
+		a.unconfirmed++
+		if a.first.Value.Error != nil {
+			a.unconfirmedErr++
+		} else {
+			a.unconfirmedSum += int64(math.Floor(a.first.Value.ResponseTime.Seconds() * 1000))
+			if a.first.Value.Status != 0 {
+				a.unconfirmedRes++
+			}
+			if a.first.Value.Status/100 == 5 {
+				a.unconfirmed5xx++
+			}
+		}
+	} else {
+		a.confirmedTimes.insert(a.first.Value.ResponseTime)
+		if a.first.Value.Error == nil {
+			a.confirmedRes.insert(a.first.Value.ResponseTime)
+		}
+	}
+	a.retryCount += len(a.first.Value.Retried)
+	if a.first.Value.Assertion != nil {
+		a.assertionCount++
+	}
+	if failure := a.first.Value.Failure(); failure != "" {
+		a.failureCount[failure]++
+	}
 	if a.first.Value.Error != nil {
 		a.errorCount++
 	} else {
 		a.count++
 		a.sumResTime += int64(math.Floor(a.first.Value.ResponseTime.Seconds() * 1000))
+		a.resTimes.insert(a.first.Value.ResponseTime)
+		for phase, value := range a.first.Value.Phases {
+			a.sumPhases[phase] += value
+		}
 		a.statusCount[a.first.Value.Status]++
 		a.statusAgg[a.first.Value.Status/100]++
 	}
@@ -113,11 +202,44 @@ func (a *Aggregator) Add(e QueueElement) (error, Alert) {
 	// Dequeue and delete from heap outdated PingLogs, and update metrics
 	for a.last.Timestamp.Add(a.duration).Before(a.first.Timestamp) {
 		last := *a.last
+		if last.Value.IsUp() {
+			a.availableCount--
+		}
+		if last.Value.Unconfirmed {
+			a.unconfirmed--
+			if last.Value.Error != nil {
+				a.unconfirmedErr--
+			} else {
+				a.unconfirmedSum -= int64(math.Floor(last.Value.ResponseTime.Seconds() * 1000))
+				if last.Value.Status != 0 {
+					a.unconfirmedRes--
+				}
+				if last.Value.Status/100 == 5 {
+					a.unconfirmed5xx--
+				}
+			}
+		} else {
+			a.confirmedTimes.delete(last.Value.ResponseTime)
+			if last.Value.Error == nil {
+				a.confirmedRes.delete(last.Value.ResponseTime)
+			}
+		}
+		a.retryCount -= len(last.Value.Retried)
+		if last.Value.Assertion != nil {
+			a.assertionCount--
+		}
+		if failure := last.Value.Failure(); failure != "" {
+			a.failureCount[failure]--
+		}
 		if last.Value.Error != nil {
 			a.errorCount--
 		} else {
 			a.count--
 			a.sumResTime -= int64(math.Floor(last.Value.ResponseTime.Seconds() * 1000))
+			a.resTimes.delete(last.Value.ResponseTime)
+			for phase, value := range last.Value.Phases {
+				a.sumPhases[phase] -= value
+			}
 			a.statusCount[last.Value.Status]--
 			a.statusAgg[last.Value.Status/100]--
 		}
@@ -126,33 +248,8 @@ func (a *Aggregator) Add(e QueueElement) (error, Alert) {
 
 	}
 
-	// Emit alerts on availability crash / resuming
-	availability := float32(a.statusCount[200]) / float32(a.count+a.errorCount)
-	if availability <= 0.80 && a.AlertStatus == false {
-		a.AlertStatus = true
-		timestamp := a.first.Timestamp
-		formattedTimestamp := formatTime(timestamp)
-		alert = Alert{
-			Init:      true,
-			Url:       a.website,
-			Timestamp: timestamp,
-			Value:     availability,
-			Message:   fmt.Sprint("[Website ", a.website, " is down !\n Availability: ", availability, "%; time: ", formattedTimestamp, "](fg-red)"),
-		}
-	} else if availability > 0.8 && a.AlertStatus == true {
-		a.AlertStatus = false
-		timestamp := a.first.Timestamp
-		formattedTimestamp := formatTime(timestamp)
-		alert = Alert{
-			Init:      true,
-			Url:       a.website,
-			Timestamp: timestamp,
-			Value:     availability,
-			Message:   fmt.Sprint("[Website ", a.website, " is up again !\n Availability: ", availability, "%; time: ", formattedTimestamp, "](fg-green)"),
-		}
-	}
-
-	return nil, alert
+	// Emit alerts on rules being triggered / cleared
+	return a.evaluateRules(), nil
 }
 
 func (a *Aggregator) GetAvgResTime() (float32, error) {
@@ -173,7 +270,7 @@ func (a *Aggregator) GetAvailability() (float32, error) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
-	out := float32(a.statusCount[200]) / float32(a.count+a.errorCount)
+	out := float32(a.availableCount) / float32(a.count+a.errorCount)
 
 	return out, nil
 }
@@ -182,7 +279,7 @@ func (a *Aggregator) GetStatusShare(status int) (float32, error) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
-	out := float32(a.statusCount[status]) / float32(a.count)
+	out := float32(a.statusCount[status]) / float32(a.responseCount())
 
 	return out, nil
 }
@@ -191,19 +288,87 @@ func (a *Aggregator) GetStatusAgg(firstDigit int) (float32, error) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
-	out := float32(a.statusAgg[firstDigit]) / float32(a.count)
+	out := float32(a.statusAgg[firstDigit]) / float32(a.responseCount())
 
 	return out, nil
 }
 
+// Number of successful checks with a status, ie excluding non-HTTP ones
+func (a *Aggregator) GetResponseCount() (int, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.responseCount(), nil
+}
+
+// Must be called with the aggregator's mutex held
+func (a *Aggregator) responseCount() int {
+	return a.count - a.statusCount[0]
+}
+
 func (a *Aggregator) GetMaxResTime() (float32, error) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
+	if len(a.heap.values) < 2 {
+		return -1., nil
+	}
 	out := float32(math.Floor(a.heap.getMax().Value.ResponseTime.Seconds() * 1000))
 	return out, nil
 }
 
+// Response time at percentile p (0 < p <= 100), -1 if no response was aggregated
+func (a *Aggregator) GetPercentileResTime(p float64) (float32, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if p <= 0 || p > 100 {
+		return 0, errors.New("INVALID PERCENTILE " + fmt.Sprint(p))
+	}
+	if a.resTimes.len() == 0 {
+		return -1., nil
+	}
+	out := float32(math.Floor(a.resTimes.percentile(p).Seconds() * 1000))
+	return out, nil
+}
+
+// Average duration of phase over successful requests in ms, -1 if there are none
+func (a *Aggregator) GetAvgPhaseTime(phase int) (float32, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	err := validatePhase(phase)
+	if err != nil {
+		return 0, err
+	}
+	if a.count == 0 {
+		return -1., nil
+	}
+	return float32(a.sumPhases[phase].Seconds()*1000) / float32(a.count), nil
+}
+
+// Maximum duration of phase over successful requests in ms, -1 if there are none
+// Unlike the response time, it is not kept in a heap: the queue is scanned in O(n)
+func (a *Aggregator) GetMaxPhaseTime(phase int) (float32, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	err := validatePhase(phase)
+	if err != nil {
+		return 0, err
+	}
+	if a.count == 0 {
+		return -1., nil
+	}
+	var max time.Duration
+	for e := a.last; e != nil; e = e.next {
+		if e.Value.Error == nil && e.Value.Phases[phase] > max {
+			max = e.Value.Phases[phase]
+		}
+	}
+	return float32(max.Seconds() * 1000), nil
+}
+
 func (a *Aggregator) GetErrorCount() (int, error) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
@@ -211,6 +376,43 @@ func (a *Aggregator) GetErrorCount() (int, error) {
 	return a.errorCount, nil
 }
 
+// Number of unsuccessful checks per category of failure, assertion failures included
+func (a *Aggregator) GetFailureCounts() (map[string]int, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	out := make(map[string]int)
+	for failure, count := range a.failureCount {
+		if count > 0 {
+			out[failure] = count
+		}
+	}
+	return out, nil
+}
+
+// Number of unsuccessful checks not confirmed yet, counted as available
+func (a *Aggregator) GetUnconfirmedCount() (int, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.unconfirmed, nil
+}
+
+// Number of attempts retried within checks
+func (a *Aggregator) GetRetryCount() (int, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.retryCount, nil
+}
+
+func (a *Aggregator) GetAssertionFailureCount() (int, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.assertionCount, nil
+}
+
 func (a *Aggregator) GetCount() (int, error) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
@@ -234,6 +436,31 @@ func (a *Aggregator) GetDuration() time.Duration {
 	return a.duration
 }
 
+func (c *CheckCounter) add(log *PingLog) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if log.Error != nil {
+		c.counts[0]++
+	} else if log.Status == 0 {
+		c.counts[STATUS_NONE]++
+	} else {
+		c.counts[log.Status]++
+	}
+}
+
+// Copy of the counts, by status code
+func (c *CheckCounter) GetCounts() map[int]uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	out := make(map[int]uint64, len(c.counts))
+	for status, count := range c.counts {
+		out[status] = count
+	}
+	return out
+}
+
 func formatTime(time time.Time) string {
 	return time.String()[:19]
 }
@@ -25,7 +25,7 @@ func TestAggregator_Alerts(t *testing.T) {
 
 	// Aggregate each element
 	for idx, qEl := range queueEls {
-		err, alert := agg.Short.Add(qEl)
+		err, alert := agg.Get(WINDOW_SHORT).Add(qEl)
 		if err != nil {
 			t.Error(err)
 		}
@@ -38,7 +38,7 @@ func TestAggregator_Alerts(t *testing.T) {
 				t.Error("Log #", idx, "should have raised an alert, but got:", alert)
 			}
 		}
-		availability, err := agg.Short.GetAvailability()
+		availability, err := agg.Get(WINDOW_SHORT).GetAvailability()
 		if err != nil {
 			t.Error("An error occurred while retrieving Availability:", err)
 		}
@@ -63,11 +63,11 @@ func TestAggregator_Count(t *testing.T) {
 	}
 
 	for idx, qEl := range queueEls {
-		err, _ := agg.Short.Add(qEl)
+		err, _ := agg.Get(WINDOW_SHORT).Add(qEl)
 		if err != nil {
 			t.Error("An error occurred while aggregating log:", err)
 		}
-		count, err := agg.Short.GetCount()
+		count, err := agg.Get(WINDOW_SHORT).GetCount()
 		if err != nil {
 			t.Error("An error occurred while retrieving count:", err)
 		}
@@ -96,11 +96,11 @@ func TestAggregator_GetAvgResTime(t *testing.T) {
 	}
 
 	for idx, qEl := range queueEls {
-		err, _ := agg.Short.Add(qEl)
+		err, _ := agg.Get(WINDOW_SHORT).Add(qEl)
 		if err != nil {
 			t.Error("An error occurred while aggregating log:", err)
 		}
-		avgRT, err := agg.Short.GetAvgResTime()
+		avgRT, err := agg.Get(WINDOW_SHORT).GetAvgResTime()
 		if err != nil {
 			t.Error("An error occurred while retrieving count:", err)
 		}
@@ -130,16 +130,16 @@ func TestAggregator_GetMaxResTime(t *testing.T) {
 	}
 
 	for idx, qEl := range queueEls {
-		err, _ := agg.Short.Add(qEl)
+		err, _ := agg.Get(WINDOW_SHORT).Add(qEl)
 		if err != nil {
 			t.Error("An error occurred while aggregating log:", err)
 		}
 		t.Log("heap:")
-		c, err := agg.Short.GetCount()
-		for i, e := range agg.Short.heap.values {
+		c, err := agg.Get(WINDOW_SHORT).GetCount()
+		for i, e := range agg.Get(WINDOW_SHORT).heap.values {
 			t.Log("index:", i, "value:", e.Value.ResponseTime, "storedIndex:", e.heapIndex, "count:", c)
 		}
-		maxRT, err := agg.Short.GetMaxResTime()
+		maxRT, err := agg.Get(WINDOW_SHORT).GetMaxResTime()
 		if err != nil {
 			t.Error("An error occurred while retrieving max res time:", err)
 		}
@@ -172,25 +172,25 @@ func TestAggregator_GetStatusCount(t *testing.T) {
 	}
 
 	for idx, qEl := range queueEls {
-		err, _ := agg.Short.Add(qEl)
+		err, _ := agg.Get(WINDOW_SHORT).Add(qEl)
 		if err != nil {
 			t.Error("An error occurred while aggregating log:", err)
 		}
-		count200, err := agg.Short.GetStatusCount(200)
+		count200, err := agg.Get(WINDOW_SHORT).GetStatusCount(200)
 		if err != nil {
 			t.Error("An error occurred while retrieving count of status 200:", err)
 		}
-		count500, err := agg.Short.GetStatusCount(500)
+		count500, err := agg.Get(WINDOW_SHORT).GetStatusCount(500)
 		if err != nil {
 			t.Error("An error occurred while retrieving count of status 500:", err)
 		}
 
-		count401, err := agg.Short.GetStatusCount(401)
+		count401, err := agg.Get(WINDOW_SHORT).GetStatusCount(401)
 		if err != nil {
 			t.Error("An error occurred while retrieving count of status 401:", err)
 		}
 
-		count, err := agg.Short.GetCount()
+		count, err := agg.Get(WINDOW_SHORT).GetCount()
 		if err != nil {
 			t.Error("An error occurred while retrieving count:", err)
 		}
@@ -268,3 +268,156 @@ var pingLogs = []*PingLog{
 		Time:         time.Date(2018, 11, 11, 11, 17, 30, 0, time.Local),
 	},
 }
+
+func TestAggregator_AlertKinds(t *testing.T) {
+	expectedKinds := map[int]AlertKind{3: ALERT_DOWN, 6: ALERT_RECOVERED, 7: ALERT_DOWN}
+
+	agg := NewAggregators("http://www.example.com")
+	for idx, log := range pingLogs {
+		_, alert := agg.Get(WINDOW_SHORT).Add(QueueElement{Timestamp: log.Time, Value: log})
+		if !alert.Init {
+			continue
+		}
+		if alert.Kind != expectedKinds[idx] {
+			t.Error("Log #", idx, "raised a", alert.Kind, "alert, expected", expectedKinds[idx])
+		}
+	}
+}
+
+func TestAggregator_Phases(t *testing.T) {
+	agg := newAggregator("http://www.example.com", 2*time.Second, nil)
+	now := time.Now()
+	logs := []*PingLog{
+		{Time: now, Status: 200, Phases: Phases{2 * time.Millisecond, 10 * time.Millisecond, 0, 80 * time.Millisecond, 4 * time.Millisecond}},
+		{Time: now.Add(time.Second), Status: 200, Phases: Phases{4 * time.Millisecond, 20 * time.Millisecond, 0, 40 * time.Millisecond, 2 * time.Millisecond}},
+		{Time: now.Add(2 * time.Second), Error: fmt.Errorf("timeout"), Phases: Phases{0, 0, 0, 900 * time.Millisecond, 0}},
+	}
+
+	avg, err := agg.GetAvgPhaseTime(PHASE_TTFB)
+	if err != nil || avg != -1 {
+		t.Error("Expected -1 before any check, got", avg, err)
+	}
+	for _, log := range logs {
+		_, err = agg.Aggregate(QueueElement{Timestamp: log.Time, Value: log})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	avg, _ = agg.GetAvgPhaseTime(PHASE_CONNECT)
+	if avg != 15 {
+		t.Error("Expected an average connect time of 15 ms, got", avg)
+	}
+	max, _ := agg.GetMaxPhaseTime(PHASE_TTFB)
+	if max != 80 {
+		t.Error("Expected a max time to first byte of 80 ms, errors excluded, got", max)
+	}
+	max, _ = agg.GetMaxPhaseTime(PHASE_TLS)
+	if max != 0 {
+		t.Error("Expected no TLS handshake, got", max)
+	}
+
+	// First log expires
+	_, err = agg.Aggregate(QueueElement{Timestamp: now.Add(2500 * time.Millisecond), Value: &PingLog{Time: now.Add(2500 * time.Millisecond), Status: 200}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	avg, _ = agg.GetAvgPhaseTime(PHASE_DNS)
+	if avg != 2 {
+		t.Error("Expected an average DNS time of 2 ms after expiry, got", avg)
+	}
+	max, _ = agg.GetMaxPhaseTime(PHASE_TTFB)
+	if max != 40 {
+		t.Error("Expected a max time to first byte of 40 ms after expiry, got", max)
+	}
+
+	_, err = agg.GetMaxPhaseTime(PHASE_COUNT)
+	if err == nil {
+		t.Error("Expected an error for an invalid phase")
+	}
+}
+
+func TestAggregator_Unconfirmed(t *testing.T) {
+	rules := []AlertRule{
+		{Metric: METRIC_AVAILABILITY, Comparison: "<=", Threshold: 0.8},
+		{Metric: METRIC_ERROR_RATE, Comparison: ">", Threshold: 0.5},
+	}
+	agg := newAggregator("http://www.example.com", 2*time.Second, rules)
+	now := time.Now()
+	logs := []*PingLog{
+		{Time: now, Status: 200, Retried: []string{FAILURE_TIMEOUT}},
+		{Time: now.Add(time.Second), Error: fmt.Errorf("timeout"), Unconfirmed: true, Retried: []string{FAILURE_TIMEOUT, FAILURE_TIMEOUT}},
+		{Time: now.Add(time.Second), Error: fmt.Errorf("timeout"), Unconfirmed: true},
+		{Time: now.Add(2 * time.Second), Error: fmt.Errorf("timeout"), Unconfirmed: true},
+	}
+	for _, log := range logs {
+		alerts, err := agg.Aggregate(QueueElement{Timestamp: log.Time, Value: log})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(alerts) != 0 {
+			t.Error("Unconfirmed failures raised alerts", alerts)
+		}
+	}
+	availability, _ := agg.GetAvailability()
+	errCount, _ := agg.GetErrorCount()
+	unconfirmed, _ := agg.GetUnconfirmedCount()
+	retries, _ := agg.GetRetryCount()
+	if availability != 1 || errCount != 3 || unconfirmed != 3 || retries != 3 {
+		t.Error("Expected raw errors and confirmed availability, got", availability, errCount, unconfirmed, retries)
+	}
+
+	// Confirmed failure, the first log expires
+	log := &PingLog{Time: now.Add(2500 * time.Millisecond), Error: fmt.Errorf("timeout")}
+	alerts, _ := agg.Aggregate(QueueElement{Timestamp: log.Time, Value: log})
+	if len(alerts) != 1 || alerts[0].Metric != METRIC_AVAILABILITY {
+		t.Error("Expected the confirmed failure to raise an availability alert only, got", alerts)
+	}
+	availability, _ = agg.GetAvailability()
+	retries, _ = agg.GetRetryCount()
+	if availability != 0.75 || retries != 2 {
+		t.Error("Expected an availability of 0.75 and 2 retries after expiry, got", availability, retries)
+	}
+}
+
+func TestAggregator_UnconfirmedLatencyAnd5xx(t *testing.T) {
+	// As with confirm=3, slow 5xx responses only count once 3 of them came in a row
+	rules := []AlertRule{
+		{Metric: METRIC_5XX_SHARE, Comparison: ">=", Threshold: 0.5},
+		{Metric: METRIC_AVG_LATENCY, Comparison: ">", Threshold: 100},
+		{Metric: METRIC_MAX_LATENCY, Comparison: ">", Threshold: 100},
+		{Metric: METRIC_PERCENTILE, Percentile: 90, Comparison: ">", Threshold: 100},
+	}
+	agg := newAggregator("http://www.example.com", time.Minute, rules)
+	now := time.Now()
+	logs := []*PingLog{
+		{Time: now, Status: 200, ResponseTime: 50 * time.Millisecond},
+		{Time: now.Add(time.Second), Status: 503, ResponseTime: 500 * time.Millisecond, Unconfirmed: true},
+		{Time: now.Add(2 * time.Second), Status: 503, ResponseTime: 500 * time.Millisecond, Unconfirmed: true},
+	}
+	for _, log := range logs {
+		alerts, err := agg.Aggregate(QueueElement{Timestamp: log.Time, Value: log})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(alerts) != 0 {
+			t.Error("Unconfirmed responses raised alerts", alerts)
+		}
+	}
+	max, _ := agg.GetMaxResTime()
+	share, _ := agg.GetStatusAgg(5)
+	if max != 500 || share < 0.66 {
+		t.Error("Expected raw measures to include unconfirmed responses, got", max, share)
+	}
+
+	// Confirmed slow 5xx response
+	log := &PingLog{Time: now.Add(3 * time.Second), Status: 503, ResponseTime: 500 * time.Millisecond}
+	alerts, _ := agg.Aggregate(QueueElement{Timestamp: log.Time, Value: log})
+	if len(alerts) != len(rules) {
+		t.Fatal("Expected the confirmed response to trigger every rule, got", alerts)
+	}
+	for idx, value := range []float32{0.5, 275, 500, 500} {
+		if alerts[idx].Value != value {
+			t.Error("Expected", rules[idx].MetricName(), "of", value, "got", alerts[idx].Value)
+		}
+	}
+}
@@ -1,6 +1,18 @@
 package monitor
 
-import "time"
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+type AlertKind int
+
+const (
+	ALERT_INFO      AlertKind = iota // Informative message, eg website registered
+	ALERT_DOWN                       // Website availability dropped under threshold
+	ALERT_RECOVERED                  // Website availability is back over threshold
+)
 
 type Alert struct {
 	Url       string
@@ -8,4 +20,26 @@ type Alert struct {
 	Value     float32
 	Message   string
 	Init      bool
+	Kind      AlertKind
+	Metric    string // Metric of the rule raising a down or recovered alert
+}
+
+func (k AlertKind) String() string {
+	switch k {
+	case ALERT_DOWN:
+		return "down"
+	case ALERT_RECOVERED:
+		return "up"
+	default:
+		return "info"
+	}
+}
+
+// Matches termui markup, eg "[text](fg-red)"
+var markup = regexp.MustCompile(`\[([^\]]*)\]\([a-z-,]+\)`)
+
+// Message without termui markup, on a single line
+func (a Alert) PlainMessage() string {
+	message := markup.ReplaceAllString(a.Message, "$1")
+	return strings.Join(strings.Fields(message), " ")
 }
@@ -0,0 +1,153 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Checks performed on every response of a website
+type Assertions struct {
+	Status      []StatusRange     // Acceptable status codes, 200 only if empty
+	Contains    []string          // Substrings the body must contain
+	NotContains []string          // Substrings the body must not contain
+	Matches     []*regexp.Regexp  // Patterns the body must match
+	NotMatches  []*regexp.Regexp  // Patterns the body must not match
+	JSONPaths   map[string]string // Expected values in a JSON body, by dot-separated path
+	MaxBodySize int64             // Maximum body size in bytes, unlimited if 0
+}
+
+// Inclusive range of status codes
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+// Parse a status range, either "200" or "200-299"
+func ParseStatusRange(str string) (StatusRange, error) {
+	bounds := strings.SplitN(strings.TrimSpace(str), "-", 2)
+	min, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return StatusRange{}, errors.New("INVALID STATUS RANGE " + str)
+	}
+	max := min
+	if len(bounds) == 2 {
+		max, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if err != nil || max < min {
+			return StatusRange{}, errors.New("INVALID STATUS RANGE " + str)
+		}
+	}
+	return StatusRange{Min: min, Max: max}, nil
+}
+
+// Whether the response body has to be read
+func (a *Assertions) needsBody() bool {
+	return len(a.Contains) > 0 || len(a.NotContains) > 0 ||
+		len(a.Matches) > 0 || len(a.NotMatches) > 0 ||
+		len(a.JSONPaths) > 0 || a.MaxBodySize > 0
+}
+
+// Check status code
+func (a *Assertions) checkStatus(status int) error {
+	if len(a.Status) == 0 {
+		if status != 200 {
+			return fmt.Errorf("ASSERTION FAILED: STATUS %d IS NOT ACCEPTED", status)
+		}
+		return nil
+	}
+	for _, r := range a.Status {
+		if status >= r.Min && status <= r.Max {
+			return nil
+		}
+	}
+	return fmt.Errorf("ASSERTION FAILED: STATUS %d IS NOT ACCEPTED", status)
+}
+
+// Check response body
+func (a *Assertions) checkBody(body []byte) error {
+	if a.MaxBodySize > 0 && int64(len(body)) > a.MaxBodySize {
+		return fmt.Errorf("ASSERTION FAILED: BODY EXCEEDS %d BYTES", a.MaxBodySize)
+	}
+	for _, str := range a.Contains {
+		if !bytes.Contains(body, []byte(str)) {
+			return errors.New("ASSERTION FAILED: BODY DOES NOT CONTAIN " + strconv.Quote(str))
+		}
+	}
+	for _, str := range a.NotContains {
+		if bytes.Contains(body, []byte(str)) {
+			return errors.New("ASSERTION FAILED: BODY CONTAINS " + strconv.Quote(str))
+		}
+	}
+	for _, re := range a.Matches {
+		if !re.Match(body) {
+			return errors.New("ASSERTION FAILED: BODY DOES NOT MATCH " + re.String())
+		}
+	}
+	for _, re := range a.NotMatches {
+		if re.Match(body) {
+			return errors.New("ASSERTION FAILED: BODY MATCHES " + re.String())
+		}
+	}
+	if len(a.JSONPaths) == 0 {
+		return nil
+	}
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return errors.New("ASSERTION FAILED: BODY IS NOT VALID JSON")
+	}
+	for path, expected := range a.JSONPaths {
+		value, found := lookupJSONPath(document, path)
+		if !found {
+			return errors.New("ASSERTION FAILED: NO VALUE AT JSON PATH " + path)
+		}
+		if value != expected {
+			return errors.New("ASSERTION FAILED: JSON PATH " + path + " IS " + strconv.Quote(value) + ", EXPECTED " + strconv.Quote(expected))
+		}
+	}
+	return nil
+}
+
+// Resolve a dot-separated path ("$.data.items.0.status") in a decoded JSON document
+func lookupJSONPath(document interface{}, path string) (string, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	current := document
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			switch node := current.(type) {
+			case map[string]interface{}:
+				value, exists := node[key]
+				if !exists {
+					return "", false
+				}
+				current = value
+			case []interface{}:
+				idx, err := strconv.Atoi(key)
+				if err != nil || idx < 0 || idx >= len(node) {
+					return "", false
+				}
+				current = node[idx]
+			default:
+				return "", false
+			}
+		}
+	}
+	switch value := current.(type) {
+	case nil:
+		return "null", true
+	case string:
+		return value, true
+	case json.Number:
+		return value.String(), true
+	case bool:
+		return strconv.FormatBool(value), true
+	default:
+		encoded, _ := json.Marshal(value)
+		return string(encoded), true
+	}
+}
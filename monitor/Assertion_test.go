@@ -0,0 +1,129 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestParseStatusRange(t *testing.T) {
+	r, err := ParseStatusRange("200-299")
+	if err != nil || r.Min != 200 || r.Max != 299 {
+		t.Error("Range does not match expectation:", r, err)
+	}
+	r, err = ParseStatusRange("301")
+	if err != nil || r.Min != 301 || r.Max != 301 {
+		t.Error("Range does not match expectation:", r, err)
+	}
+	if _, err = ParseStatusRange("299-200"); err == nil {
+		t.Error("Inverted range should return a non-nil error")
+	}
+	if _, err = ParseStatusRange("abc"); err == nil {
+		t.Error("Invalid range should return a non-nil error")
+	}
+}
+
+func TestAssertions_Status(t *testing.T) {
+	var defaults Assertions
+	if defaults.checkStatus(200) != nil {
+		t.Error("Status 200 should be accepted by default")
+	}
+	if defaults.checkStatus(204) == nil {
+		t.Error("Status 204 should not be accepted by default")
+	}
+
+	custom := Assertions{Status: []StatusRange{{200, 299}, {301, 301}}}
+	for _, status := range []int{200, 204, 301} {
+		if custom.checkStatus(status) != nil {
+			t.Error("Status", status, "should be accepted")
+		}
+	}
+	for _, status := range []int{302, 404, 500} {
+		if custom.checkStatus(status) == nil {
+			t.Error("Status", status, "should not be accepted")
+		}
+	}
+}
+
+func TestAssertions_Body(t *testing.T) {
+	body := []byte(`{"status":"ok","checks":[{"name":"db","up":true}],"version":3}`)
+	cases := []struct {
+		assertions Assertions
+		pass       bool
+	}{
+		{Assertions{Contains: []string{`"ok"`}}, true},
+		{Assertions{Contains: []string{"error"}}, false},
+		{Assertions{NotContains: []string{"error"}}, true},
+		{Assertions{NotContains: []string{"db"}}, false},
+		{Assertions{Matches: []*regexp.Regexp{regexp.MustCompile(`"version":\d+`)}}, true},
+		{Assertions{NotMatches: []*regexp.Regexp{regexp.MustCompile(`"up":false`)}}, true},
+		{Assertions{NotMatches: []*regexp.Regexp{regexp.MustCompile(`"up":true`)}}, false},
+		{Assertions{JSONPaths: map[string]string{"status": "ok", "$.checks.0.up": "true", "version": "3"}}, true},
+		{Assertions{JSONPaths: map[string]string{"status": "degraded"}}, false},
+		{Assertions{JSONPaths: map[string]string{"checks.1.name": "db"}}, false},
+		{Assertions{MaxBodySize: 1024}, true},
+		{Assertions{MaxBodySize: 10}, false},
+	}
+	for idx, c := range cases {
+		err := c.assertions.checkBody(body)
+		if c.pass && err != nil {
+			t.Error("Case #", idx, "should pass, but got:", err)
+		}
+		if !c.pass && err == nil {
+			t.Error("Case #", idx, "should fail")
+		}
+	}
+}
+
+func TestPinger_Assertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html>Internal error, please retry</html>")
+	}))
+	defer server.Close()
+
+	website := Website{
+		Url:           server.URL,
+		CheckInterval: 100,
+		Assertions:    Assertions{NotContains: []string{"error"}},
+	}
+	checker := newHTTPChecker(website)
+	log := checker.Check(context.Background())
+	if log.Error != nil {
+		t.Fatal("Unexpected error:", log.Error)
+	}
+	if log.Status != 200 {
+		t.Error("Status does not match expectation:", log.Status)
+	}
+	if log.Assertion == nil {
+		t.Error("Assertion should have failed")
+	}
+	if log.IsAvailable() {
+		t.Error("Log failing an assertion should not be available")
+	}
+}
+
+func TestAggregator_AssertionAvailability(t *testing.T) {
+	agg := NewAggregators("http://www.example.com")
+	start := time.Date(2018, 11, 11, 11, 10, 0, 0, time.Local)
+	logs := []*PingLog{
+		{Status: 200, Asserted: true, Time: start},
+		{Status: 200, Asserted: true, Assertion: fmt.Errorf("ASSERTION FAILED"), Time: start.Add(time.Second)},
+		{Status: 204, Asserted: true, Time: start.Add(2 * time.Second)},
+		{Status: 200, Asserted: true, Assertion: fmt.Errorf("ASSERTION FAILED"), Time: start.Add(3 * time.Second)},
+	}
+	for _, log := range logs {
+		agg.Get(WINDOW_SHORT).Add(QueueElement{Value: log, Timestamp: log.Time})
+	}
+	availability, _ := agg.Get(WINDOW_SHORT).GetAvailability()
+	if availability != 0.5 {
+		t.Error("Availability does not match expectation:", availability)
+	}
+	failures, _ := agg.Get(WINDOW_SHORT).GetAssertionFailureCount()
+	if failures != 2 {
+		t.Error("Assertion failure count does not match expectation:", failures)
+	}
+}
@@ -0,0 +1,171 @@
+package monitor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Metrics of certificate alerts
+const (
+	METRIC_CERT_EXPIRY   = "cert_expiry"
+	METRIC_CERT_HOSTNAME = "cert_hostname"
+)
+
+// Alert when the certificate expires in less than 30, 7 and 1 days
+func DefaultCertExpiryDays() []int {
+	return []int{30, 7, 1}
+}
+
+// Leaf TLS certificate presented by a website on a check
+type Certificate struct {
+	Subject          string
+	Issuer           string
+	SANs             []string // DNS names and IP addresses the certificate is valid for
+	NotAfter         time.Time
+	DaysToExpiry     int    // At the time of the check, negative once expired
+	ChainError       string // Chain validation error, empty if the chain is valid
+	HostnameMismatch bool   // The certificate is not valid for the website's host
+}
+
+// Certificate presented in a TLS handshake, nil if there is none
+// handshakeErr is the error of the handshake, which holds the certificates if their validation failed
+func newCertificate(state tls.ConnectionState, handshakeErr error, checked time.Time) *Certificate {
+	certs := state.PeerCertificates
+	var verifyErr *tls.CertificateVerificationError
+	if errors.As(handshakeErr, &verifyErr) {
+		certs = verifyErr.UnverifiedCertificates
+	}
+	if len(certs) == 0 {
+		return nil
+	}
+	leaf := certs[0]
+	cert := &Certificate{
+		Subject:      leaf.Subject.CommonName,
+		Issuer:       issuerName(leaf),
+		SANs:         append([]string{}, leaf.DNSNames...),
+		NotAfter:     leaf.NotAfter,
+		DaysToExpiry: int(math.Floor(leaf.NotAfter.Sub(checked).Hours() / 24)),
+	}
+	for _, ip := range leaf.IPAddresses {
+		cert.SANs = append(cert.SANs, ip.String())
+	}
+	if verifyErr != nil {
+		cert.ChainError = verifyErr.Err.Error()
+		var hostnameErr x509.HostnameError
+		cert.HostnameMismatch = errors.As(verifyErr.Err, &hostnameErr)
+	}
+	return cert
+}
+
+func issuerName(cert *x509.Certificate) string {
+	if cert.Issuer.CommonName != "" {
+		return cert.Issuer.CommonName
+	}
+	if len(cert.Issuer.Organization) > 0 {
+		return cert.Issuer.Organization[0]
+	}
+	return cert.Issuer.String()
+}
+
+// Check days to expiry thresholds are positive
+func ValidateCertExpiryDays(days []int) error {
+	for _, day := range days {
+		if day <= 0 {
+			return fmt.Errorf("CERTIFICATE EXPIRY THRESHOLDS MUST BE POSITIVE, GOT %d", day)
+		}
+	}
+	return nil
+}
+
+// Latest certificate of a website, raising alerts when its expiry crosses thresholds,
+// and when it stops or starts matching the website's host
+type CertificateWatch struct {
+	thresholds []int // Days to expiry, in decreasing order
+	latest     *Certificate
+	crossed    int  // Number of thresholds crossed by the latest certificate
+	expired    bool // The latest certificate has expired
+	mismatch   bool
+	mutex      sync.Mutex
+}
+
+// Watch alerting when days to expiry drop below each of thresholds
+func NewCertificateWatch(thresholds []int) *CertificateWatch {
+	sorted := append([]int{}, thresholds...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+	return &CertificateWatch{thresholds: sorted}
+}
+
+// Record the certificate of a check, if any, and return the alerts it raises
+func (w *CertificateWatch) check(website string, log *PingLog) []Alert {
+	cert := log.Certificate
+	if cert == nil {
+		return nil
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.latest = cert
+	alerts := make([]Alert, 0)
+	crossed := 0
+	for _, threshold := range w.thresholds {
+		if cert.DaysToExpiry < threshold {
+			crossed++
+		}
+	}
+	alert := Alert{
+		Init:      true,
+		Url:       website,
+		Timestamp: log.Time,
+		Value:     float32(cert.DaysToExpiry),
+		Metric:    METRIC_CERT_EXPIRY,
+	}
+	expired := cert.DaysToExpiry < 0
+	formattedTimestamp := formatTime(log.Time)
+	switch {
+	case expired && !w.expired:
+		alert.Kind = ALERT_DOWN
+		alert.Message = fmt.Sprint("[Website ", website, " certificate has expired !\n Expired on: ", formatTime(cert.NotAfter), "; time: ", formattedTimestamp, "](fg-red)")
+		alerts = append(alerts, alert)
+	case crossed > w.crossed:
+		alert.Kind = ALERT_DOWN
+		alert.Message = fmt.Sprint("[Website ", website, " certificate expires in ", cert.DaysToExpiry, " days !\n Expires on: ", formatTime(cert.NotAfter), "; time: ", formattedTimestamp, "](fg-red)")
+		alerts = append(alerts, alert)
+	case crossed == 0 && w.crossed > 0:
+		alert.Kind = ALERT_RECOVERED
+		alert.Message = fmt.Sprint("[Website ", website, " certificate was renewed !\n Expires on: ", formatTime(cert.NotAfter), "; time: ", formattedTimestamp, "](fg-green)")
+		alerts = append(alerts, alert)
+	}
+	w.crossed = crossed
+	w.expired = expired
+
+	if cert.HostnameMismatch != w.mismatch {
+		w.mismatch = cert.HostnameMismatch
+		alert := Alert{
+			Init:      true,
+			Url:       website,
+			Timestamp: log.Time,
+			Metric:    METRIC_CERT_HOSTNAME,
+			Kind:      ALERT_RECOVERED,
+			Message:   fmt.Sprint("[Website ", website, " certificate matches its host again !\n time: ", formattedTimestamp, "](fg-green)"),
+		}
+		if cert.HostnameMismatch {
+			alert.Kind = ALERT_DOWN
+			alert.Message = fmt.Sprint("[Website ", website, " certificate does not match its host !\n ", cert.ChainError, "; time: ", formattedTimestamp, "](fg-red)")
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+// Latest certificate seen, nil if there is none
+func (w *CertificateWatch) Latest() *Certificate {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.latest
+}
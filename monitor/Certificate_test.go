@@ -0,0 +1,147 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Checker of server trusting its certificate, checking it is valid for serverName if not empty
+func newTLSChecker(server *httptest.Server, serverName string) *httpChecker {
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	checker := newHTTPChecker(Website{Url: server.URL, CheckInterval: 1000})
+	checker.client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{
+		RootCAs:    pool,
+		ServerName: serverName,
+	}
+	return checker
+}
+
+func TestCertificate_Valid(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	checker := newTLSChecker(server, "")
+	log := checker.Check(context.Background())
+	if log.Error != nil {
+		t.Fatal("Unexpected error:", log.Error)
+	}
+	cert := log.Certificate
+	if cert == nil {
+		t.Fatal("Certificate was not recorded")
+	}
+	if cert.ChainError != "" || cert.HostnameMismatch {
+		t.Error("Expected a valid certificate, got", cert.ChainError)
+	}
+	if cert.DaysToExpiry != int(server.Certificate().NotAfter.Sub(log.Time).Hours()/24) {
+		t.Error("Days to expiry do not match expectation:", cert.DaysToExpiry)
+	}
+	if cert.Issuer == "" || len(cert.SANs) == 0 {
+		t.Error("Expected an issuer and SANs, got", cert.Issuer, cert.SANs)
+	}
+}
+
+func TestCertificate_HostnameMismatch(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	checker := newTLSChecker(server, "suricata.invalid")
+	log := checker.Check(context.Background())
+	if log.Error == nil {
+		t.Error("Expected the check to fail")
+	}
+	if log.Certificate == nil || !log.Certificate.HostnameMismatch || log.Certificate.ChainError == "" {
+		t.Error("Expected a hostname mismatch, got", log.Certificate)
+	}
+}
+
+func TestCertificate_UnknownAuthority(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	checker := newHTTPChecker(Website{Url: server.URL, CheckInterval: 1000})
+	log := checker.Check(context.Background())
+	if log.Error == nil {
+		t.Error("Expected the check to fail")
+	}
+	if log.Certificate == nil || log.Certificate.ChainError == "" || log.Certificate.HostnameMismatch {
+		t.Error("Expected a chain validation error, got", log.Certificate)
+	}
+}
+
+func TestCertificate_NoTLS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	checker := newHTTPChecker(Website{Url: server.URL, CheckInterval: 1000})
+	log := checker.Check(context.Background())
+	if log.Certificate != nil {
+		t.Error("Expected no certificate for http, got", log.Certificate)
+	}
+}
+
+func TestCertificateWatch_Alerts(t *testing.T) {
+	watch := NewCertificateWatch([]int{7, 30, 1})
+	checks := []struct {
+		days     int
+		mismatch bool
+		kinds    []AlertKind
+	}{
+		{45, false, nil},
+		{20, false, []AlertKind{ALERT_DOWN}},
+		{19, false, nil},
+		{5, false, []AlertKind{ALERT_DOWN}},
+		{-1, true, []AlertKind{ALERT_DOWN, ALERT_DOWN}},
+		{90, false, []AlertKind{ALERT_RECOVERED, ALERT_RECOVERED}},
+	}
+	for _, check := range checks {
+		cert := &Certificate{DaysToExpiry: check.days, HostnameMismatch: check.mismatch}
+		alerts := watch.check("https://example.com", &PingLog{Time: time.Now(), Certificate: cert})
+		if len(alerts) != len(check.kinds) {
+			t.Error("Expected", len(check.kinds), "alerts at", check.days, "days, got", alerts)
+			continue
+		}
+		for idx, alert := range alerts {
+			if alert.Kind != check.kinds[idx] {
+				t.Error("Expected a", check.kinds[idx], "alert at", check.days, "days, got", alert.Kind)
+			}
+		}
+		if watch.Latest() != cert {
+			t.Error("Latest certificate was not recorded")
+		}
+	}
+
+	// Checks without certificate keep the latest one
+	watch.check("https://example.com", &PingLog{Time: time.Now()})
+	if watch.Latest() == nil {
+		t.Error("Latest certificate was dropped")
+	}
+}
+
+func TestCertificateWatch_Expired(t *testing.T) {
+	// Expiry alerts once the certificate expires, even if no threshold is crossed on that check
+	for _, thresholds := range [][]int{DefaultCertExpiryDays(), {}} {
+		watch := NewCertificateWatch(thresholds)
+		expired := 0
+		for _, days := range []int{45, 0, -1, -2} {
+			cert := &Certificate{DaysToExpiry: days}
+			for _, alert := range watch.check("https://example.com", &PingLog{Time: time.Now(), Certificate: cert}) {
+				if strings.Contains(alert.Message, "has expired") {
+					if days != -1 {
+						t.Error("Unexpected expired alert at", days, "days with thresholds", thresholds)
+					}
+					expired++
+				}
+			}
+		}
+		if expired != 1 {
+			t.Error("Expected a single expired alert with thresholds", thresholds, "got", expired)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Record types DNS websites resolve
+const (
+	RECORD_A     = "A"
+	RECORD_AAAA  = "AAAA"
+	RECORD_CNAME = "CNAME"
+)
+
+// Port of resolvers given without one
+const DEFAULT_DNS_PORT = "53"
+
+// Resolves a name, eg dns://8.8.8.8:53/example.com?type=A&expect=93.184.216.34
+// The resolver is the system one when the url has no host, eg dns:///example.com
+type dnsChecker struct {
+	website  string
+	name     string
+	record   string
+	expected []string // Records the answer must contain, in any order
+	resolver *net.Resolver
+	timeout  time.Duration
+}
+
+func newDNSProbe(out chan<- PingLog, website Website) (Probe, error) {
+	checker, err := newDNSChecker(website.Url, checkTimeout(website))
+	if err != nil {
+		return nil, err
+	}
+	return NewPinger(out, website, checker), nil
+}
+
+// Parse a dns:// url, see dnsChecker
+func newDNSChecker(website string, timeout time.Duration) (*dnsChecker, error) {
+	target, err := url.Parse(website)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimPrefix(target.Path, "/")
+	if name == "" {
+		return nil, errors.New("DNS WEBSITES MUST BE dns://[resolver]/name, GOT " + website)
+	}
+	query := target.Query()
+	record := strings.ToUpper(query.Get("type"))
+	if record == "" {
+		record = RECORD_A
+	}
+	if record != RECORD_A && record != RECORD_AAAA && record != RECORD_CNAME {
+		return nil, errors.New("UNSUPPORTED DNS RECORD TYPE " + record + ", EXPECTED A, AAAA OR CNAME")
+	}
+	expected := make([]string, 0, len(query["expect"]))
+	for _, value := range query["expect"] {
+		normalized, err := normalizeRecord(record, value)
+		if err != nil {
+			return nil, err
+		}
+		expected = append(expected, normalized)
+	}
+
+	checker := &dnsChecker{
+		website:  website,
+		name:     name,
+		record:   record,
+		expected: expected,
+		resolver: net.DefaultResolver,
+		timeout:  timeout,
+	}
+	if target.Host != "" {
+		if net.ParseIP(target.Hostname()) == nil {
+			return nil, errors.New("DNS RESOLVER MUST BE AN IP ADDRESS, GOT " + target.Host)
+		}
+		address := target.Host
+		if target.Port() == "" {
+			address = net.JoinHostPort(target.Hostname(), DEFAULT_DNS_PORT)
+		}
+		checker.resolver = newResolver(address)
+		// Bypass search domains, which are meant for the system resolver
+		checker.name = strings.TrimSuffix(name, ".") + "."
+	}
+	return checker, nil
+}
+
+// Resolver sending its queries to address, instead of the resolvers of the system
+func newResolver(address string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// Canonical form of a record, for comparisons
+func normalizeRecord(record string, value string) (string, error) {
+	if record == RECORD_CNAME {
+		return strings.ToLower(strings.TrimSuffix(value, ".")), nil
+	}
+	ip := net.ParseIP(value)
+	if ip == nil || (record == RECORD_A) != (ip.To4() != nil) {
+		return "", errors.New("INVALID " + record + " RECORD " + value)
+	}
+	return ip.String(), nil
+}
+
+// Resolve the name, then check the answer is not empty and contains the expected records
+// Resolution errors, including unknown names, fail the check, unexpected answers fail its assertion
+func (d *dnsChecker) Check(ctx context.Context) PingLog {
+	startTime := time.Now()
+	log := PingLog{
+		Time:     startTime,
+		Website:  d.website,
+		Asserted: true,
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	answer, err := d.resolve(ctx)
+	log.ResponseTime = time.Now().Sub(startTime)
+	log.Phases[PHASE_DNS] = log.ResponseTime
+	if err != nil {
+		log.Error = err
+		return log
+	}
+	log.Assertion = d.validate(answer)
+	return log
+}
+
+// Normalized records of the answer
+func (d *dnsChecker) resolve(ctx context.Context) ([]string, error) {
+	switch d.record {
+	case RECORD_CNAME:
+		cname, err := d.resolver.LookupCNAME(ctx, d.name)
+		if err != nil {
+			return nil, err
+		}
+		cname, _ = normalizeRecord(RECORD_CNAME, cname)
+		name, _ := normalizeRecord(RECORD_CNAME, d.name)
+		// Names without CNAME record are their own canonical name
+		if cname == "" || cname == name {
+			return []string{}, nil
+		}
+		return []string{cname}, nil
+	default:
+		network := "ip4"
+		if d.record == RECORD_AAAA {
+			network = "ip6"
+		}
+		ips, err := d.resolver.LookupIP(ctx, network, d.name)
+		if err != nil {
+			return nil, err
+		}
+		answer := make([]string, 0, len(ips))
+		for _, ip := range ips {
+			answer = append(answer, ip.String())
+		}
+		return answer, nil
+	}
+}
+
+func (d *dnsChecker) validate(answer []string) error {
+	if len(answer) == 0 {
+		return errors.New("NO " + d.record + " RECORD FOR " + d.name)
+	}
+	found := make(map[string]bool, len(answer))
+	for _, record := range answer {
+		found[record] = true
+	}
+	for _, expected := range d.expected {
+		if !found[expected] {
+			sort.Strings(answer)
+			return errors.New("EXPECTED " + d.record + " RECORD " + expected + ", GOT " + strings.Join(answer, " "))
+		}
+	}
+	return nil
+}
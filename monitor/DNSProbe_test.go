@@ -0,0 +1,171 @@
+package monitor
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+)
+
+// Record types and response codes of the test DNS server
+const (
+	TEST_TYPE_A     = 1
+	TEST_TYPE_CNAME = 5
+	TEST_TYPE_AAAA  = 28
+	TEST_NXDOMAIN   = 3
+)
+
+type testRecord struct {
+	rtype uint16
+	data  []byte
+}
+
+// Encode a domain name as DNS labels
+func encodeName(name string) []byte {
+	encoded := make([]byte, 0, len(name)+2)
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		encoded = append(encoded, byte(len(label)))
+		encoded = append(encoded, label...)
+	}
+	return append(encoded, 0)
+}
+
+// Serve records over UDP on a local port until the returned connection is closed
+// Queries get the records of their type and CNAME records, unknown names get NXDOMAIN
+func serveDNS(t *testing.T, records map[string][]testRecord) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			query := buffer[:n]
+			// Question: labels, then type and class
+			end := 12
+			labels := make([]string, 0)
+			for end < n && query[end] != 0 {
+				labels = append(labels, string(query[end+1:end+1+int(query[end])]))
+				end += 1 + int(query[end])
+			}
+			end += 5
+			qtype := binary.BigEndian.Uint16(query[end-4 : end-2])
+
+			answers, known := records[strings.ToLower(strings.Join(labels, "."))]
+			response := make([]byte, 12, 512)
+			copy(response, query[:2])
+			binary.BigEndian.PutUint16(response[2:], 0x8180)
+			if !known {
+				binary.BigEndian.PutUint16(response[2:], 0x8180|TEST_NXDOMAIN)
+			}
+			binary.BigEndian.PutUint16(response[4:], 1)
+			response = append(response, query[12:end]...)
+			count := 0
+			for _, record := range answers {
+				if record.rtype != qtype && record.rtype != TEST_TYPE_CNAME {
+					continue
+				}
+				count++
+				response = append(response, 0xc0, 12) // Pointer to the question's name
+				response = binary.BigEndian.AppendUint16(response, record.rtype)
+				response = binary.BigEndian.AppendUint16(response, 1)
+				response = binary.BigEndian.AppendUint32(response, 60)
+				response = binary.BigEndian.AppendUint16(response, uint16(len(record.data)))
+				response = append(response, record.data...)
+			}
+			binary.BigEndian.PutUint16(response[6:], uint16(count))
+			conn.WriteTo(response, addr)
+		}
+	}()
+	return conn
+}
+
+func newTestDNSServer(t *testing.T) net.PacketConn {
+	return serveDNS(t, map[string][]testRecord{
+		"api.suricata.test": {
+			{TEST_TYPE_A, net.ParseIP("192.0.2.1").To4()},
+			{TEST_TYPE_A, net.ParseIP("192.0.2.2").To4()},
+			{TEST_TYPE_AAAA, net.ParseIP("2001:db8::1")},
+		},
+		"www.suricata.test": {
+			{TEST_TYPE_CNAME, encodeName("api.suricata.test")},
+		},
+	})
+}
+
+func TestDNSProbe_Records(t *testing.T) {
+	server := newTestDNSServer(t)
+	defer server.Close()
+	resolver := "dns://" + server.LocalAddr().String()
+
+	checks := []struct {
+		url     string
+		failure string
+	}{
+		{"/api.suricata.test", ""},
+		{"/api.suricata.test?expect=192.0.2.2&expect=192.0.2.1", ""},
+		{"/api.suricata.test?type=aaaa&expect=2001:db8:0::1", ""},
+		{"/www.suricata.test?type=CNAME&expect=API.suricata.test.", ""},
+		{"/api.suricata.test?expect=192.0.2.3", FAILURE_ASSERTION},
+		{"/www.suricata.test?type=CNAME&expect=web.suricata.test", FAILURE_ASSERTION},
+		{"/api.suricata.test?type=CNAME", FAILURE_ASSERTION},
+		{"/missing.suricata.test", FAILURE_DNS},
+	}
+	for _, check := range checks {
+		url := resolver + check.url
+		probe, err := NewProbe(nil, Website{Url: url, CheckInterval: 1000})
+		if err != nil {
+			t.Error("Unexpected invalid url", url, err)
+			continue
+		}
+		log := probe.Check(context.Background())
+		if log.Failure() != check.failure {
+			t.Error("Expected failure", check.failure, "for", url, "got", log.Failure(), log.Error, log.Assertion)
+		}
+		if log.Website != url || log.Status != 0 || log.Phases[PHASE_DNS] <= 0 {
+			t.Error("Expected the resolution time of", url, "got", log)
+		}
+	}
+}
+
+func TestDNSProbe_ResolverDown(t *testing.T) {
+	server := newTestDNSServer(t)
+	address := server.LocalAddr().String()
+	server.Close()
+
+	probe, err := NewProbe(nil, Website{Url: "dns://" + address + "/api.suricata.test", CheckInterval: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := probe.Check(context.Background())
+	if log.Error == nil {
+		t.Fatal("Expected the check to fail")
+	}
+	if log.Failure() != FAILURE_DNS {
+		t.Error("Expected a DNS failure, got", log.Failure(), log.Error)
+	}
+}
+
+func TestDNSProbe_ValidateUrl(t *testing.T) {
+	urls := map[string]bool{
+		"dns:///example.com":                          true,
+		"dns://8.8.8.8/example.com?type=AAAA":         true,
+		"dns://[2001:4860:4860::8888]:53/example.com": true,
+		"dns://8.8.8.8":                               false,
+		"dns://resolver.example.com/example.com":      false,
+		"dns:///example.com?type=MX":                  false,
+		"dns:///example.com?expect=not-an-ip":         false,
+		"dns:///example.com?type=AAAA&expect=1.2.3.4": false,
+	}
+	for url, valid := range urls {
+		_, err := NewProbe(nil, Website{Url: url, CheckInterval: 1000})
+		if (err == nil) != valid {
+			t.Error("Unexpected validation of", url, err)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package monitor
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type EventType int
+
+const (
+	EVENT_REGISTERED   EventType = iota // Website registered
+	EVENT_UNREGISTERED                  // Website unregistered
+	EVENT_STARTED                       // Monitoring of a website started
+	EVENT_PAUSED                        // Monitoring of a website paused
+	EVENT_REJECTED                      // Operation on a website refused, eg registered twice
+	EVENT_CHECK                         // Check of a website completed
+	EVENT_THRESHOLD                     // Alert rule triggered, eg availability under its threshold
+	EVENT_RECOVERED                     // Alert rule cleared
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EVENT_REGISTERED:
+		return "registered"
+	case EVENT_UNREGISTERED:
+		return "unregistered"
+	case EVENT_STARTED:
+		return "started"
+	case EVENT_PAUSED:
+		return "paused"
+	case EVENT_REJECTED:
+		return "rejected"
+	case EVENT_CHECK:
+		return "check"
+	case EVENT_THRESHOLD:
+		return "threshold"
+	case EVENT_RECOVERED:
+		return "recovered"
+	default:
+		return "unknown"
+	}
+}
+
+// Types of the events carrying an Alert, ie all but EVENT_CHECK
+func AlertEvents() []EventType {
+	return []EventType{EVENT_REGISTERED, EVENT_UNREGISTERED, EVENT_STARTED, EVENT_PAUSED, EVENT_REJECTED, EVENT_THRESHOLD, EVENT_RECOVERED}
+}
+
+type Event struct {
+	Type  EventType
+	Url   string
+	Time  time.Time
+	Log   *PingLog // Log of the check, for EVENT_CHECK only
+	Alert *Alert   // Alert raised, for the other types
+}
+
+// What a subscription does with the events published while its buffer is full
+type OverflowPolicy int
+
+const (
+	OVERFLOW_DROP_NEWEST OverflowPolicy = iota // Drop the published event
+	OVERFLOW_DROP_OLDEST                       // Drop the oldest buffered event, to make room for the published one
+	OVERFLOW_BLOCK                             // Wait for room, slowing the publisher down
+)
+
+// Events buffered by subscriptions not setting theirs
+const DEFAULT_EVENT_BUFFER = 64
+
+type SubscriptionOptions struct {
+	Buffer   int            // Events buffered, DEFAULT_EVENT_BUFFER if 0
+	Overflow OverflowPolicy // OVERFLOW_DROP_NEWEST if not set
+	Types    []EventType    // Types of the events received, all if empty
+}
+
+// Publishes events to subscriptions, each consuming them independently
+// A slow subscriber only delays publishers if its policy is OVERFLOW_BLOCK
+type EventBus struct {
+	subscriptions []*Subscription
+	closed        bool
+	mutex         sync.RWMutex
+}
+
+type Subscription struct {
+	bus      *EventBus
+	events   chan Event
+	overflow OverflowPolicy
+	types    map[EventType]bool // Types received, nil for all
+	dropped  uint64
+	closed   chan bool
+	once     sync.Once
+	mutex    sync.Mutex // Held while delivering, so that events is not closed meanwhile
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscriptions: make([]*Subscription, 0)}
+}
+
+// Receive the events published from now on
+func (b *EventBus) Subscribe(options SubscriptionOptions) (*Subscription, error) {
+	if options.Buffer < 0 {
+		return nil, errors.New("SUBSCRIPTION BUFFER MUST NOT BE NEGATIVE")
+	}
+	if options.Overflow < OVERFLOW_DROP_NEWEST || options.Overflow > OVERFLOW_BLOCK {
+		return nil, errors.New("UNKNOWN OVERFLOW POLICY")
+	}
+	if options.Buffer == 0 {
+		options.Buffer = DEFAULT_EVENT_BUFFER
+	}
+	subscription := &Subscription{
+		bus:      b,
+		events:   make(chan Event, options.Buffer),
+		overflow: options.Overflow,
+		closed:   make(chan bool),
+	}
+	if len(options.Types) > 0 {
+		subscription.types = make(map[EventType]bool)
+		for _, kind := range options.Types {
+			subscription.types[kind] = true
+		}
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return nil, errors.New("EVENT BUS IS CLOSED")
+	}
+	b.subscriptions = append(b.subscriptions, subscription)
+	return subscription, nil
+}
+
+// Send event to the subscriptions receiving its type
+func (b *EventBus) Publish(event Event) {
+	b.mutex.RLock()
+	subscriptions := append([]*Subscription(nil), b.subscriptions...)
+	b.mutex.RUnlock()
+	for _, subscription := range subscriptions {
+		subscription.deliver(event)
+	}
+}
+
+// Close all subscriptions, events published afterwards are discarded
+func (b *EventBus) Close() {
+	b.mutex.Lock()
+	b.closed = true
+	subscriptions := b.subscriptions
+	b.mutex.Unlock()
+	for _, subscription := range subscriptions {
+		subscription.Close()
+	}
+}
+
+func (b *EventBus) remove(subscription *Subscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for idx, other := range b.subscriptions {
+		if other == subscription {
+			b.subscriptions = append(b.subscriptions[:idx:idx], b.subscriptions[idx+1:]...)
+			return
+		}
+	}
+}
+
+// Events received, closed once the subscription is closed and its buffer drained
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Number of events dropped as the buffer was full
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Stop receiving events, publishers blocked on the subscription are released
+// Buffered events can still be read from Events
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.remove(s)
+		close(s.closed)
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		close(s.events)
+	})
+}
+
+func (s *Subscription) deliver(event Event) {
+	if s.types != nil && !s.types[event.Type] {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	select {
+	case <-s.closed:
+		return
+	default:
+	}
+
+	switch s.overflow {
+	case OVERFLOW_BLOCK:
+		select {
+		case s.events <- event:
+		case <-s.closed:
+		}
+	case OVERFLOW_DROP_OLDEST:
+		for {
+			select {
+			case s.events <- event:
+				return
+			default:
+			}
+			select {
+			case <-s.events:
+				atomic.AddUint64(&s.dropped, 1)
+			default:
+			}
+		}
+	default:
+		select {
+		case s.events <- event:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestSubscription(t *testing.T, bus *EventBus, options SubscriptionOptions) *Subscription {
+	subscription, err := bus.Subscribe(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return subscription
+}
+
+func TestEvent_Overflow(t *testing.T) {
+	bus := NewEventBus()
+	newest := newTestSubscription(t, bus, SubscriptionOptions{Buffer: 2})
+	oldest := newTestSubscription(t, bus, SubscriptionOptions{Buffer: 2, Overflow: OVERFLOW_DROP_OLDEST})
+	checks := newTestSubscription(t, bus, SubscriptionOptions{Types: []EventType{EVENT_CHECK}})
+	for _, url := range []string{"a", "b", "c"} {
+		bus.Publish(Event{Type: EVENT_REGISTERED, Url: url})
+	}
+
+	expected := map[*Subscription][]string{newest: {"a", "b"}, oldest: {"b", "c"}}
+	for subscription, urls := range expected {
+		if subscription.Dropped() != 1 {
+			t.Error("Expected a dropped event, got", subscription.Dropped())
+		}
+		for _, url := range urls {
+			event := <-subscription.Events()
+			if event.Url != url {
+				t.Error("Expected event of", url, "got", event.Url)
+			}
+		}
+	}
+	if len(checks.Events()) != 0 {
+		t.Error("Expected events of other types to be filtered out, got", len(checks.Events()))
+	}
+
+	_, err := bus.Subscribe(SubscriptionOptions{Buffer: -1})
+	if err == nil {
+		t.Error("Expected an error for a negative buffer")
+	}
+	bus.Close()
+	if _, open := <-checks.Events(); open {
+		t.Error("Expected subscriptions to be closed with the bus")
+	}
+	if _, err = bus.Subscribe(SubscriptionOptions{}); err == nil {
+		t.Error("Expected an error subscribing to a closed bus")
+	}
+}
+
+func TestEvent_Block(t *testing.T) {
+	bus := NewEventBus()
+	blocking := newTestSubscription(t, bus, SubscriptionOptions{Buffer: 1, Overflow: OVERFLOW_BLOCK})
+	published := make(chan bool)
+	go func() {
+		bus.Publish(Event{Type: EVENT_STARTED, Url: "a"})
+		bus.Publish(Event{Type: EVENT_STARTED, Url: "b"})
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		t.Fatal("Expected the publisher to wait for room")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if event := <-blocking.Events(); event.Url != "a" {
+		t.Error("Expected the first event, got", event.Url)
+	}
+	<-published
+	if blocking.Dropped() != 0 {
+		t.Error("Expected no dropped event, got", blocking.Dropped())
+	}
+
+	// Closing the subscription releases the publisher
+	released := make(chan bool)
+	go func() {
+		bus.Publish(Event{Type: EVENT_STARTED, Url: "c"})
+		bus.Publish(Event{Type: EVENT_STARTED, Url: "d"})
+		bus.Publish(Event{Type: EVENT_STARTED, Url: "e"})
+		close(released)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	blocking.Close()
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Error("Publisher was not released")
+	}
+	urls := make([]string, 0)
+	for event := range blocking.Events() {
+		urls = append(urls, event.Url)
+	}
+	if len(urls) != 1 || urls[0] != "b" {
+		t.Error("Expected the buffered event to be readable once closed, got", urls)
+	}
+}
+
+func TestEvent_Orchestrator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}))
+	defer server.Close()
+	o, err := NewOrchestrator(OrchestratorOptions{Windows: []Window{{"1m", time.Minute}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	go o.Run(context.Background())
+
+	// Nobody reads the events of the slow subscriber, it does not slow the orchestrator down
+	slow, _ := o.Subscribe(SubscriptionOptions{Buffer: 1})
+	events, _ := o.Subscribe(SubscriptionOptions{Buffer: 100, Overflow: OVERFLOW_BLOCK})
+	err = o.Register(Website{Url: server.URL, CheckInterval: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Register(Website{Url: server.URL, CheckInterval: 10})
+	o.Start(server.URL)
+
+	received := make(map[EventType]int)
+	timeout := time.After(time.Second)
+	for received[EVENT_THRESHOLD] == 0 {
+		select {
+		case event := <-events.Events():
+			received[event.Type]++
+			if event.Url != server.URL {
+				t.Error("Unexpected event of", event.Url)
+			}
+			if (event.Type == EVENT_CHECK) != (event.Log != nil && event.Alert == nil) {
+				t.Error("Unexpected payload of", event.Type, "event")
+			}
+		case <-timeout:
+			t.Fatal("Expected the availability threshold to be crossed, got", received)
+		}
+	}
+	o.Pause(server.URL)
+
+	// Toggling publishes the same events as Start and Pause
+	o.Toggle(server.URL)
+	o.Toggle(server.URL)
+	for received[EVENT_PAUSED] < 2 {
+		event := <-events.Events()
+		received[event.Type]++
+	}
+	if received[EVENT_STARTED] != 2 {
+		t.Error("Expected toggling to publish started and paused events, got", received)
+	}
+	o.Unregister(server.URL)
+	for received[EVENT_UNREGISTERED] == 0 {
+		event := <-events.Events()
+		received[event.Type]++
+	}
+
+	for _, kind := range []EventType{EVENT_REGISTERED, EVENT_REJECTED, EVENT_STARTED, EVENT_CHECK, EVENT_PAUSED} {
+		if received[kind] == 0 {
+			t.Error("Expected", kind, "events, got", received)
+		}
+	}
+	if slow.Dropped() == 0 {
+		t.Error("Expected the events of the slow subscriber to be dropped")
+	}
+}
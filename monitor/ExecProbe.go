@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"time"
+)
+
+// Bytes of stdout and stderr kept in the logs of exec checks
+const MAX_OUTPUT_SNIPPET = 512
+
+// Grace period for the output of a timed out command to be closed, eg by its children, once it is killed
+const EXEC_WAIT_DELAY = time.Second
+
+// Runs the command of a website, eg exec://orders-queue, exit code 0 meaning success
+// The command is killed when the timeout expires
+type execChecker struct {
+	website string
+	command []string
+	timeout time.Duration
+}
+
+func newExecProbe(out chan<- PingLog, website Website) (Probe, error) {
+	if len(website.Command) == 0 || website.Command[0] == "" {
+		return nil, errors.New("EXEC WEBSITE " + website.Url + " HAS NO COMMAND")
+	}
+	checker := &execChecker{
+		website: website.Url,
+		command: website.Command,
+		timeout: checkTimeout(website),
+	}
+	return NewPinger(out, website, checker), nil
+}
+
+// Run the command, its run duration being the response time
+// The command is killed as well when ctx is cancelled
+func (e *execChecker) Check(ctx context.Context) PingLog {
+	startTime := time.Now()
+	log := PingLog{
+		Time:     startTime,
+		Website:  e.website,
+		Asserted: true,
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, e.command[0], e.command[1:]...)
+	cmd.WaitDelay = EXEC_WAIT_DELAY
+	stdout, stderr := newSnippet(MAX_OUTPUT_SNIPPET), newSnippet(MAX_OUTPUT_SNIPPET)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	err := cmd.Run()
+	log.ResponseTime = time.Now().Sub(startTime)
+	log.Stdout, log.Stderr = stdout.String(), stderr.String()
+	if ctx.Err() == context.DeadlineExceeded {
+		// The command was killed, its exit status does not tell why
+		log.Error = ctx.Err()
+	} else if err != nil {
+		log.Error = err
+	}
+	return log
+}
+
+// Writer keeping the first bytes written to it, and discarding the rest
+type snippet struct {
+	buffer bytes.Buffer
+	limit  int
+}
+
+func newSnippet(limit int) *snippet {
+	return &snippet{limit: limit}
+}
+
+func (s *snippet) Write(p []byte) (int, error) {
+	if room := s.limit - s.buffer.Len(); room > 0 {
+		if len(p) > room {
+			s.buffer.Write(p[:room])
+		} else {
+			s.buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (s *snippet) String() string {
+	return s.buffer.String()
+}
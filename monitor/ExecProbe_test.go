@@ -0,0 +1,70 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newExecChecker(t *testing.T, timeout time.Duration, script string) Probe {
+	probe, err := NewProbe(nil, Website{
+		Url:           "exec://check",
+		CheckInterval: 1000,
+		Timeout:       timeout,
+		Command:       []string{"sh", "-c", script},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return probe
+}
+
+func TestExecProbe_Exit(t *testing.T) {
+	checks := []struct {
+		script  string
+		failure string
+		stdout  string
+		stderr  bool
+	}{
+		{"echo queued 12; echo slow consumer >&2", "", "queued 12\n", true},
+		{"echo queued 5000; exit 2", FAILURE_EXIT, "queued 5000\n", false},
+		{"exec /does/not/exist", FAILURE_EXIT, "", true},
+	}
+	for _, check := range checks {
+		log := newExecChecker(t, 0, check.script).Check(context.Background())
+		if log.Failure() != check.failure {
+			t.Error("Expected failure", check.failure, "for", check.script, "got", log.Failure(), log.Error)
+		}
+		if log.Stdout != check.stdout || (log.Stderr != "") != check.stderr {
+			t.Errorf("Unexpected output of %q: %q %q", check.script, log.Stdout, log.Stderr)
+		}
+		if log.Website != "exec://check" || log.ResponseTime <= 0 {
+			t.Error("Expected the run duration of", check.script, "got", log)
+		}
+	}
+}
+
+func TestExecProbe_Timeout(t *testing.T) {
+	log := newExecChecker(t, 50*time.Millisecond, "exec sleep 5").Check(context.Background())
+	if log.Failure() != FAILURE_TIMEOUT {
+		t.Error("Expected a timeout, got", log.Failure(), log.Error)
+	}
+	if log.ResponseTime > 2*time.Second {
+		t.Error("Command was not killed on timeout, ran for", log.ResponseTime)
+	}
+}
+
+func TestExecProbe_Snippet(t *testing.T) {
+	log := newExecChecker(t, 0, "seq 1000; seq 1000 >&2").Check(context.Background())
+	if log.Error != nil {
+		t.Fatal("Unexpected error:", log.Error)
+	}
+	if len(log.Stdout) != MAX_OUTPUT_SNIPPET || len(log.Stderr) != MAX_OUTPUT_SNIPPET {
+		t.Error("Expected output snippets of", MAX_OUTPUT_SNIPPET, "bytes, got", len(log.Stdout), len(log.Stderr))
+	}
+
+	_, err := NewProbe(nil, Website{Url: "exec://check", CheckInterval: 1000})
+	if err == nil {
+		t.Error("Expected an error for an exec website without command")
+	}
+}
@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"sort"
+	"syscall"
+)
+
+// Categories of unsuccessful checks
+const (
+	FAILURE_DNS       = "dns"
+	FAILURE_REFUSED   = "refused"
+	FAILURE_RESET     = "reset"
+	FAILURE_TIMEOUT   = "timeout"
+	FAILURE_TLS       = "tls"
+	FAILURE_ASSERTION = "assertion"
+	FAILURE_REDIRECTS = "redirects"
+	FAILURE_EXIT      = "exit"
+	FAILURE_OTHER     = "other"
+)
+
+// Redirects followed before a check fails
+const MAX_REDIRECTS = 10
+
+var ErrTooManyRedirects = errors.New("TOO MANY REDIRECTS")
+
+// Redirect policy of pingers, failing with ErrTooManyRedirects after MAX_REDIRECTS redirects
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= MAX_REDIRECTS {
+		return ErrTooManyRedirects
+	}
+	return nil
+}
+
+// Category of the failure of the check, empty if it is available
+func (l *PingLog) Failure() string {
+	if l.Error == nil {
+		if l.Assertion != nil {
+			return FAILURE_ASSERTION
+		}
+		return ""
+	}
+	return classifyError(l.Error)
+}
+
+func classifyError(err error) string {
+	var dnsErr *net.DNSError
+	var verifyErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var netErr net.Error
+	var exitErr *exec.ExitError
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return FAILURE_DNS
+	case errors.As(err, &verifyErr), errors.As(err, &recordErr), errors.As(err, &alertErr),
+		errors.As(err, &unknownAuthorityErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return FAILURE_TLS
+	case errors.Is(err, ErrTooManyRedirects):
+		return FAILURE_REDIRECTS
+	case errors.As(err, &exitErr):
+		return FAILURE_EXIT
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return FAILURE_TIMEOUT
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return FAILURE_REFUSED
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return FAILURE_RESET
+	default:
+		return FAILURE_OTHER
+	}
+}
+
+// Categories of counts, sorted by decreasing count then name
+func sortedFailures(counts map[string]int) []string {
+	failures := make([]string, 0, len(counts))
+	for failure, count := range counts {
+		if count > 0 {
+			failures = append(failures, failure)
+		}
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if counts[failures[i]] != counts[failures[j]] {
+			return counts[failures[i]] > counts[failures[j]]
+		}
+		return failures[i] < failures[j]
+	})
+	return failures
+}
@@ -0,0 +1,99 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFailure_Classify(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+	hangUp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer hangUp.Close()
+	loop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/again", http.StatusFound)
+	}))
+	defer loop.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer secure.Close()
+	teapot := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer teapot.Close()
+
+	// Port nothing listens on anymore
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "http://" + listener.Addr().String()
+	listener.Close()
+
+	cases := []struct {
+		website  Website
+		expected string
+	}{
+		{Website{Url: "http://suricata.invalid", CheckInterval: 1000}, FAILURE_DNS},
+		{Website{Url: closed, CheckInterval: 1000}, FAILURE_REFUSED},
+		{Website{Url: hangUp.URL, CheckInterval: 1000}, FAILURE_RESET},
+		{Website{Url: slow.URL, CheckInterval: 1000, Timeout: 50 * time.Millisecond}, FAILURE_TIMEOUT},
+		{Website{Url: secure.URL, CheckInterval: 1000}, FAILURE_TLS},
+		{Website{Url: loop.URL, CheckInterval: 1000}, FAILURE_REDIRECTS},
+		{Website{Url: teapot.URL, CheckInterval: 1000, Assertions: Assertions{Status: []StatusRange{{200, 299}}}}, FAILURE_ASSERTION},
+		{Website{Url: teapot.URL, CheckInterval: 1000, Assertions: Assertions{Status: []StatusRange{{418, 418}}}}, ""},
+	}
+	for _, c := range cases {
+		checker := newHTTPChecker(c.website)
+		log := checker.Check(context.Background())
+		if log.Failure() != c.expected {
+			t.Error("Expected failure", c.expected, "for", c.website.Url, "got", log.Failure(), log.Error)
+		}
+	}
+
+	log := PingLog{Error: errors.New("unexpected")}
+	if log.Failure() != FAILURE_OTHER {
+		t.Error("Expected unknown errors to be classified as other, got", log.Failure())
+	}
+}
+
+func TestAggregator_GetFailureCounts(t *testing.T) {
+	agg := newAggregator("http://www.example.com", 2*time.Second, nil)
+	now := time.Now()
+	logs := []*PingLog{
+		{Time: now, Error: &net.DNSError{Err: "no such host", Name: "example.com"}},
+		{Time: now.Add(time.Second), Status: 500, Asserted: true, Assertion: errors.New("status")},
+		{Time: now.Add(2 * time.Second), Error: &net.DNSError{Err: "no such host", Name: "example.com"}},
+		{Time: now.Add(2500 * time.Millisecond), Status: 200},
+	}
+	for _, log := range logs {
+		_, err := agg.Aggregate(QueueElement{Timestamp: log.Time, Value: log})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	counts, _ := agg.GetFailureCounts()
+	if len(counts) != 2 || counts[FAILURE_DNS] != 1 || counts[FAILURE_ASSERTION] != 1 {
+		t.Error("Expected 1 DNS and 1 assertion failure once the first log expired, got", counts)
+	}
+
+	sorted := sortedFailures(map[string]int{FAILURE_TIMEOUT: 1, FAILURE_DNS: 3, FAILURE_RESET: 1, FAILURE_TLS: 0})
+	expected := []string{FAILURE_DNS, FAILURE_RESET, FAILURE_TIMEOUT}
+	if len(sorted) != len(expected) {
+		t.Fatal("Expected", expected, "got", sorted)
+	}
+	for idx := range expected {
+		if sorted[idx] != expected[idx] {
+			t.Error("Expected", expected, "got", sorted)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package monitor
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"time"
+)
+
+// Sends an HTTP request, eg https://example.com, and checks the response against assertions
+type httpChecker struct {
+	url        string
+	method     string
+	headers    map[string]string
+	body       string
+	assertions Assertions
+	client     *http.Client
+}
+
+func newHTTPProbe(out chan<- PingLog, website Website) (Probe, error) {
+	return NewPinger(out, website, newHTTPChecker(website)), nil
+}
+
+func newHTTPChecker(website Website) *httpChecker {
+	timeout := checkTimeout(website)
+	tr := &http.Transport{
+		DisableKeepAlives:     true,
+		ResponseHeaderTimeout: timeout,
+	}
+	client := &http.Client{Transport: tr, CheckRedirect: checkRedirect}
+	if website.Timeout > 0 {
+		client.Timeout = website.Timeout
+	}
+	method := strings.ToUpper(website.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	return &httpChecker{
+		url:        website.Url,
+		method:     method,
+		headers:    website.Headers,
+		body:       website.Body,
+		assertions: website.Assertions,
+		client:     client,
+	}
+}
+
+// Send the request, then check the response against the assertions
+// Cancelling ctx aborts the request
+func (c *httpChecker) Check(ctx context.Context) PingLog {
+	startTime := time.Now()
+	req, err := c.newRequest()
+	if err != nil {
+		return PingLog{
+			Time:         startTime,
+			Website:      c.url,
+			Error:        err,
+			ResponseTime: time.Now().Sub(startTime),
+		}
+	}
+	trace := newPhaseTrace(startTime)
+	req = req.WithContext(httptrace.WithClientTrace(ctx, trace.clientTrace()))
+	res, err := c.client.Do(req)
+	if err != nil {
+		return PingLog{
+			Time:         startTime,
+			Website:      c.url,
+			Error:        err,
+			ResponseTime: time.Now().Sub(startTime),
+			Certificate:  trace.certificate(),
+			Phases:       trace.phases(time.Time{}),
+		}
+	}
+	defer res.Body.Close()
+	log := PingLog{
+		Time:         startTime,
+		Website:      c.url,
+		Status:       res.StatusCode,
+		ResponseTime: time.Now().Sub(startTime),
+		Asserted:     true,
+		Certificate:  trace.certificate(),
+	}
+	log.Assertion = c.assertions.checkStatus(res.StatusCode)
+
+	var reader io.Reader = res.Body
+	if c.assertions.MaxBodySize > 0 {
+		// Read one extra byte to detect oversized bodies
+		reader = io.LimitReader(res.Body, c.assertions.MaxBodySize+1)
+	}
+	if log.Assertion != nil || !c.assertions.needsBody() {
+		// Drain the body to time its transfer
+		_, err = io.Copy(ioutil.Discard, io.LimitReader(reader, MAX_DRAINED_BODY_SIZE))
+		log.Phases = trace.phases(time.Now())
+		if err != nil {
+			log.Phases[PHASE_TRANSFER] = 0
+		}
+		return log
+	}
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return PingLog{
+			Time:         startTime,
+			Website:      c.url,
+			Error:        err,
+			ResponseTime: time.Now().Sub(startTime),
+			Certificate:  log.Certificate,
+			Phases:       trace.phases(time.Time{}),
+		}
+	}
+	log.Phases = trace.phases(time.Now())
+	log.Assertion = c.assertions.checkBody(body)
+	return log
+}
+
+// Build the HTTP request sent on each check
+func (c *httpChecker) newRequest() (*http.Request, error) {
+	var body io.Reader
+	if c.body != "" {
+		body = strings.NewReader(c.body)
+	}
+	req, err := http.NewRequest(c.method, c.url, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range c.headers {
+		// net/http ignores the Host header, it has to be set on the request
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+	return req, nil
+}
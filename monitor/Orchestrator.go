@@ -1,17 +1,35 @@
 package monitor
 
 import (
+	"context"
 	"errors"
+	"sort"
 	"sync"
 	"time"
 )
 
+// Controls the probes and aggregators of websites
+// Its methods are safe to call from multiple goroutines, eg the UI, the API and config reloads,
+// events being published once no lock is held, so that their subscribers may call them too
 type Orchestrator struct {
 	pipeline    chan PingLog
-	alerts      chan Alert
-	pingers     map[string]*Pinger
+	events      *EventBus
+	probes      map[string]Probe
 	aggregators map[string]*Aggregators
 	reports     map[string]*Report
+	websites    map[string]Website // Websites as registered, before defaults are applied
+	windows     []Window           // Windows of websites not defining theirs, DefaultWindows if nil
+	scheduler   *Scheduler         // Runs the checks of schedulable probes, if set
+	closed      chan bool          // Closed by Close
+	mutex       sync.RWMutex       // Guards the fields above, and the reports
+}
+
+// Options of an Orchestrator, zero values standing for the defaults
+type OrchestratorOptions struct {
+	Pipeline  chan PingLog // Logs of the checks, aggregated by Run, created if nil
+	Alerts    chan Alert   // Alerts of the events, forwarded as they were published if set, see Subscribe
+	Windows   []Window     // Windows of websites not defining theirs, DefaultWindows if nil
+	Scheduler *Scheduler   // Runs the checks of schedulable probes, a goroutine per website if nil
 }
 
 var (
@@ -19,25 +37,102 @@ var (
 	once         sync.Once
 )
 
-// Singleton pattern
+// Singleton, see NewOrchestrator
+// The logs of pipeline are aggregated in the background until pipeline or the orchestrator is closed
 func GetOrchestrator(pipeline chan PingLog, alerts chan Alert) *Orchestrator {
 	once.Do(func() {
-		orchestrator = &Orchestrator{
-			pipeline:    pipeline,
-			alerts:      alerts,
-			pingers:     make(map[string]*Pinger),
-			aggregators: make(map[string]*Aggregators),
-			reports:     make(map[string]*Report),
-		}
+		orchestrator, _ = NewOrchestrator(OrchestratorOptions{Pipeline: pipeline, Alerts: alerts})
+		go orchestrator.Run(context.Background())
 	})
+	return orchestrator
+}
+
+// Orchestrator independent from others, eg one per tenant
+// Its logs are aggregated once Run is called, and its probes stopped by Close
+func NewOrchestrator(options OrchestratorOptions) (*Orchestrator, error) {
+	if options.Windows != nil {
+		err := ValidateWindows(options.Windows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.Pipeline == nil {
+		options.Pipeline = make(chan PingLog)
+	}
+	o := &Orchestrator{
+		pipeline:    options.Pipeline,
+		events:      NewEventBus(),
+		probes:      make(map[string]Probe),
+		aggregators: make(map[string]*Aggregators),
+		reports:     make(map[string]*Report),
+		websites:    make(map[string]Website),
+		windows:     options.Windows,
+		scheduler:   options.Scheduler,
+		closed:      make(chan bool),
+	}
+	if options.Alerts != nil {
+		// Alerts are not dropped, as they were sent on the channel before events were published
+		subscription, err := o.Subscribe(SubscriptionOptions{Overflow: OVERFLOW_BLOCK, Types: AlertEvents()})
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			for event := range subscription.Events() {
+				options.Alerts <- *event.Alert
+			}
+		}()
+	}
+	return o, nil
+}
 
-	go func() {
-		for {
-			orchestrator.AggLog(<-orchestrator.pipeline)
+// Aggregate the logs of the pipeline until ctx is cancelled, the pipeline closed or Close called
+// Returns the error of ctx once cancelled, nil once closed
+func (o *Orchestrator) Run(ctx context.Context) error {
+	for {
+		select {
+		case log, ok := <-o.pipeline:
+			if !ok {
+				return nil
+			}
+			o.AggLog(log)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-o.closed:
+			return nil
 		}
-	}()
+	}
+}
 
-	return orchestrator
+// Stop the probes of all websites and Run, and close the subscriptions to its events
+// Websites can no longer be registered nor started, but their reports can still be read
+func (o *Orchestrator) Close() error {
+	o.mutex.Lock()
+	if o.isClosed() {
+		o.mutex.Unlock()
+		return nil
+	}
+	close(o.closed)
+	for url, probe := range o.probes {
+		probe.Stop()
+		o.reports[url].Active = false
+	}
+	o.mutex.Unlock()
+	o.events.Close()
+	return nil
+}
+
+func (o *Orchestrator) isClosed() bool {
+	select {
+	case <-o.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Receive the events of the orchestrator, eg for the UI, notifiers or loggers
+func (o *Orchestrator) Subscribe(options SubscriptionOptions) (*Subscription, error) {
+	return o.events.Subscribe(options)
 }
 
 // Forward incoming PingLog to Aggregators
@@ -47,194 +142,286 @@ func (o *Orchestrator) AggLog(log PingLog) error {
 		Timestamp: log.Time,
 		next:      nil,
 	}
-	agg, exists := o.aggregators[log.Website]
-	if !exists {
-		return errors.New("NO AGGREGATOR FOR WEBSITE " + log.Website)
-	}
-	err, alert := agg.Short.Add(wrapper)
+	agg, err := o.GetAggregator(log.Website)
 	if err != nil {
 		return err
 	}
-	err, _ = agg.Medium.Add(wrapper)
-	if err != nil {
-		return err
+	alerts := make([]Alert, 0)
+	for _, aggregator := range agg.Aggregators {
+		raised, err := aggregator.Aggregate(wrapper)
+		if err != nil {
+			return err
+		}
+		alerts = append(alerts, raised...)
+	}
+	agg.Checks.add(&log)
+	alerts = append(alerts, agg.Certificates.check(log.Website, &log)...)
+	o.events.Publish(Event{Type: EVENT_CHECK, Url: log.Website, Time: log.Time, Log: &log})
+	for idx := range alerts {
+		kind := EVENT_THRESHOLD
+		if alerts[idx].Kind == ALERT_RECOVERED {
+			kind = EVENT_RECOVERED
+		}
+		o.events.Publish(Event{Type: kind, Url: log.Website, Time: alerts[idx].Timestamp, Alert: &alerts[idx]})
 	}
-	err, _ = agg.Long.Add(wrapper)
+	return nil
+}
+
+// Set aggregation windows of websites registered without windows
+func (o *Orchestrator) SetDefaultWindows(windows []Window) error {
+	err := ValidateWindows(windows)
 	if err != nil {
 		return err
 	}
-	if alert.Init {
-		o.alerts <- alert
-	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.windows = windows
 	return nil
 }
 
+// Run the checks of websites registered from now on with scheduler, instead of a goroutine each
+func (o *Orchestrator) SetScheduler(scheduler *Scheduler) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.scheduler = scheduler
+}
+
+// Publish an event about url, with an Init alert
+// Must be called without holding the mutex, as subscribers may call the orchestrator
+func (o *Orchestrator) notify(kind EventType, url string, message string) {
+	alert := Alert{
+		Url:       url,
+		Init:      true,
+		Timestamp: time.Now(),
+		Message:   message,
+		Value:     0.,
+	}
+	o.events.Publish(Event{Type: kind, Url: url, Time: alert.Timestamp, Alert: &alert})
+}
+
 // Register a new website
 func (o *Orchestrator) Register(website Website) error {
-	_, registered := o.pingers[website.Url]
+	duplicate, err := o.register(website)
+	if duplicate {
+		o.notify(EVENT_REJECTED, website.Url, "Website "+website.Url+" is already registered for monitoring, aborting")
+	}
+	if err != nil {
+		return err
+	}
+	o.notify(EVENT_REGISTERED, website.Url, "Website "+website.Url+" is registered for monitoring")
+	return nil
+}
+
+// Register website, returns whether it was already registered
+func (o *Orchestrator) register(website Website) (bool, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.isClosed() {
+		return false, errors.New("ORCHESTRATOR IS CLOSED")
+	}
+	_, registered := o.probes[website.Url]
 	if registered {
-		alert := Alert{
-			Url:       website.Url,
-			Init:      true,
-			Timestamp: time.Now(),
-			Message:   "Website " + website.Url + " is already registered for monitoring, aborting",
-			Value:     0.,
+		return true, errors.New("WEBSITE " + website.Url + " ALREADY REGISTERED")
+	}
+	// The scheduler would never check it
+	if website.CheckInterval <= 0 {
+		return false, errors.New("CHECK INTERVAL OF WEBSITE " + website.Url + " MUST BE POSITIVE")
+	}
+	definition := website
+	probe, err := NewProbe(o.pipeline, website)
+	if err != nil {
+		return false, err
+	}
+	if len(website.Windows) == 0 {
+		website.Windows = o.windows
+	}
+	if len(website.Windows) == 0 {
+		website.Windows = DefaultWindows()
+	}
+	err = ValidateWindows(website.Windows)
+	if err != nil {
+		return false, err
+	}
+	for _, rule := range website.AlertRules {
+		err = rule.ValidateFor(website.Windows)
+		if err != nil {
+			return false, err
 		}
-		o.alerts <- alert
-		return errors.New("WEBSITE " + website.Url + " ALREADY REGISTERED")
 	}
-	newPinger := NewPinger(o.pipeline, website)
-	o.pingers[website.Url] = &newPinger
-	err := o.addAggregators(website.Url)
+	err = ValidateCertExpiryDays(website.CertExpiry)
 	if err != nil {
-		return err
+		return false, err
 	}
-	o.reports[website.Url], err = NewReport(website)
+	err = validateRetries(website)
 	if err != nil {
-		return err
+		return false, err
 	}
-	alert := Alert{
-		Url:       website.Url,
-		Init:      true,
-		Timestamp: time.Now(),
-		Message:   "Website " + website.Url + " is registered for monitoring",
-		Value:     0.,
+	if _, exists := o.aggregators[website.Url]; exists {
+		return false, errors.New("WEBSITE " + website.Url + " ALREADY HAVE AGGREGATORS")
 	}
-	o.alerts <- alert
-	return nil
+	report, err := NewReport(website)
+	if err != nil {
+		return false, err
+	}
+	if scheduled, ok := probe.(schedulable); ok && o.scheduler != nil {
+		scheduled.useScheduler(o.scheduler)
+	}
+	// Everything is built, so that a website is either fully registered or not at all
+	o.probes[website.Url] = probe
+	o.aggregators[website.Url] = newAggregators(website)
+	o.reports[website.Url] = report
+	o.websites[website.Url] = definition
+	return false, nil
 }
 
-// Unregister (delete) a websitye
+// Unregister (delete) a websitye, stopping its probe
 func (o *Orchestrator) Unregister(url string) error {
-	pinger, registered := o.pingers[url]
+	o.mutex.Lock()
+	probe, registered := o.probes[url]
 	if !registered {
-		alert := Alert{
-			Url:       url,
-			Init:      true,
-			Timestamp: time.Now(),
-			Message:   "Website " + url + " is not registered, aborting",
-			Value:     0.,
-		}
-		o.alerts <- alert
+		o.mutex.Unlock()
+		o.notify(EVENT_REJECTED, url, "Website "+url+" is not registered, aborting")
 		return errors.New("WEBSITE " + url + " IS NOT REGISTERED")
 	}
-	if pinger.IsRunning {
-		alert := Alert{
-			Url:       url,
-			Init:      true,
-			Timestamp: time.Now(),
-			Message:   "Website " + url + " is still running, aborting",
-			Value:     0.,
-		}
-		o.alerts <- alert
-		return errors.New("PINGER FOR WEBSITE " + url + " IS STILL RUNNING")
-	}
-	delete(o.pingers, url)
+	// Cancel the running check, no log of url is sent afterwards
+	probe.Stop()
+	delete(o.probes, url)
 	delete(o.reports, url)
+	delete(o.websites, url)
 	err := o.deleteAggregators(url)
+	o.mutex.Unlock()
 
-	o.alerts <- Alert{
-		Url:       url,
-		Init:      true,
-		Timestamp: time.Now(),
-		Message:   "Website " + url + " is unregistered",
-		Value:     0.,
-	}
+	o.notify(EVENT_UNREGISTERED, url, "Website "+url+" is unregistered")
 	return err
 }
 
 // Start/resume monitoring a website
 func (o *Orchestrator) Start(website string) (bool, error) {
-	pinger, registered := o.pingers[website]
-	if !registered {
-		return false, errors.New("WEBSITE " + website + " IS NOT REGISTERED")
-	}
-	if !pinger.IsRunning {
-		r := o.reports[website]
-		r.Active = true
-		o.reports[website] = r
-		go pinger.Start()
-		o.alerts <- Alert{
-			Url:       website,
-			Init:      true,
-			Timestamp: time.Now(),
-			Message:   "Website " + website + " monitoring has started",
-			Value:     0.,
-		}
-		return true, nil
+	started, err := o.setActive(website, true)
+	if started {
+		o.notify(EVENT_STARTED, website, "Website "+website+" monitoring has started")
 	}
-	return false, nil
+	return started, err
 }
 
 // Pause monitoring a website
 func (o *Orchestrator) Pause(url string) (bool, error) {
-	pinger, registered := o.pingers[url]
-	if !registered {
-		return false, errors.New("WEBSITE " + url + " IS NOT REGISTERED")
-	}
-	if pinger.IsRunning {
-		r := o.reports[url]
-		r.Active = false
-		o.reports[url] = r
-		pinger.Pause()
-		o.alerts <- Alert{
-			Url:       url,
-			Init:      true,
-			Timestamp: time.Now(),
-			Message:   "Website " + url + " monitoring is paused",
-			Value:     0.,
-		}
-		return true, nil
+	paused, err := o.setActive(url, false)
+	if paused {
+		o.notify(EVENT_PAUSED, url, "Website "+url+" monitoring is paused")
 	}
-	return false, nil
+	return paused, err
 }
 
 // Start / Pause
 func (o *Orchestrator) Toggle(url string) (bool, error) {
-	pinger, registered := o.pingers[url]
+	active, err := o.toggleActive(url)
+	if err != nil {
+		return false, err
+	}
+	if active {
+		o.notify(EVENT_STARTED, url, "Website "+url+" monitoring has started")
+	} else {
+		o.notify(EVENT_PAUSED, url, "Website "+url+" monitoring is paused")
+	}
+	return active, nil
+}
+
+// Start or stop the probe of url, returns whether it was not already in that state
+// The mutex is held meanwhile, so that probes are not started once unregistered
+func (o *Orchestrator) setActive(url string, active bool) (bool, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	probe, registered := o.probes[url]
 	if !registered {
 		return false, errors.New("WEBSITE " + url + " IS NOT REGISTERED")
 	}
-	if pinger.IsRunning {
-		pinger.Pause()
+	if probe.IsRunning() == active {
 		return false, nil
-	} else {
-		go pinger.Start()
-		return true, nil
 	}
+	err := o.switchProbe(url, probe, active)
+	return err == nil, err
+}
 
+// Start the probe of url if it is stopped, stop it otherwise, returns whether it is now active
+// Reading and switching its state under the same lock, concurrent toggles do not both start it
+func (o *Orchestrator) toggleActive(url string) (bool, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	probe, registered := o.probes[url]
+	if !registered {
+		return false, errors.New("WEBSITE " + url + " IS NOT REGISTERED")
+	}
+	active := !probe.IsRunning()
+	err := o.switchProbe(url, probe, active)
+	if err != nil {
+		return false, err
+	}
+	return active, nil
+}
+
+// Start or stop probe, the one of url, must be called with the mutex held
+func (o *Orchestrator) switchProbe(url string, probe Probe, active bool) error {
+	if active && o.isClosed() {
+		return errors.New("ORCHESTRATOR IS CLOSED")
+	}
+	o.reports[url].Active = active
+	if active {
+		probe.Start(context.Background())
+	} else {
+		probe.Stop()
+	}
+	return nil
 }
 
 // Checks whether monitoring of url is active
 func (o *Orchestrator) IsActive(url string) (bool, error) {
-	pinger, registered := o.pingers[url]
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	probe, registered := o.probes[url]
 	if !registered {
 		return false, errors.New("WEBSITE " + url + " IS NOT REGISTERED")
 	}
-	return pinger.IsRunning, nil
+	return probe.IsRunning(), nil
 }
 
-// Get all registered Pingers
-func (o *Orchestrator) GetPingers() ([]*Pinger, error) {
-	var pingers []*Pinger
-	for _, pinger := range o.pingers {
-		pingers = append(pingers, pinger)
+// Get the probe monitoring url
+func (o *Orchestrator) GetProbe(url string) (Probe, error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	probe, registered := o.probes[url]
+	if !registered {
+		return nil, errors.New("WEBSITE " + url + " IS NOT REGISTERED")
 	}
-	return pingers, nil
+	return probe, nil
 }
 
-// Add aggregators for url
-func (o *Orchestrator) addAggregators(url string) error {
-	_, exists := o.aggregators[url]
-	if exists {
-		return errors.New("WEBSITE " + url + " ALREADY HAVE AGGREGATORS")
+// Get urls of all registered websites, sorted
+func (o *Orchestrator) GetUrls() []string {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	urls := make([]string, 0, len(o.probes))
+	for url := range o.probes {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// Aggregators of website, on its windows with its alert rules
+func newAggregators(website Website) *Aggregators {
+	rules := website.AlertRules
+	if len(rules) == 0 {
+		rules = DefaultAlertRules()
 	}
-	agg := NewAggregators(url)
-	o.aggregators[url] = &agg
-	return nil
+	agg := NewAggregatorsWithRules(website.Url, website.Windows, rules)
+	if len(website.CertExpiry) > 0 {
+		agg.Certificates = NewCertificateWatch(website.CertExpiry)
+	}
+	return &agg
 }
 
-// Delete aggregators for url
+// Delete aggregators for url, must be called with the mutex held
 func (o *Orchestrator) deleteAggregators(url string) error {
 	_, exists := o.aggregators[url]
 	if !exists {
@@ -246,6 +433,8 @@ func (o *Orchestrator) deleteAggregators(url string) error {
 
 // Get all registered aggregators
 func (o *Orchestrator) GetAggregators() ([]*Aggregators, error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
 	var aggregators []*Aggregators
 	for _, agg := range o.aggregators {
 		aggregators = append(aggregators, agg)
@@ -255,6 +444,8 @@ func (o *Orchestrator) GetAggregators() ([]*Aggregators, error) {
 
 // Get aggregators for url
 func (o *Orchestrator) GetAggregator(url string) (*Aggregators, error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
 	agg, exists := o.aggregators[url]
 	if !exists {
 		return nil, errors.New("NO AGGREGATOR FOR WEBSITE " + url)
@@ -262,13 +453,32 @@ func (o *Orchestrator) GetAggregator(url string) (*Aggregators, error) {
 	return agg, nil
 }
 
-// Get Report for url
+// Get the website registered for url, as it was registered
+func (o *Orchestrator) GetWebsite(url string) (Website, error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	website, registered := o.websites[url]
+	if !registered {
+		return Website{}, errors.New("WEBSITE " + url + " IS NOT REGISTERED")
+	}
+	return website, nil
+}
+
+// Get a copy of the Report for url, nil if it is not registered
 func (o *Orchestrator) GetReport(url string) *Report {
-	return o.reports[url]
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	report, exists := o.reports[url]
+	if !exists {
+		return nil
+	}
+	return report.copy()
 }
 
 // Get Summary for url
 func (o *Orchestrator) GetSummary(url string) ([][]string, error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
 	report, exists := o.reports[url]
 	if !exists {
 		return nil, errors.New("NO REPORT FOR WEBSITE " + url)
@@ -276,65 +486,87 @@ func (o *Orchestrator) GetSummary(url string) ([][]string, error) {
 	return report.Summary(), nil
 }
 
-// Update Short Term report for url
-func (o *Orchestrator) UpdateShortReport(url string) error {
-	err := o.reports[url].ShortTerm.Update(o.aggregators[url].Short)
-	return err
+// Get Report and Aggregators for url, must be called with the mutex held
+func (o *Orchestrator) reportAndAggregators(url string) (*Report, *Aggregators, error) {
+	report, exists := o.reports[url]
+	if !exists {
+		return nil, nil, errors.New("NO REPORT FOR WEBSITE " + url)
+	}
+	agg, exists := o.aggregators[url]
+	if !exists {
+		return nil, nil, errors.New("NO AGGREGATOR FOR WEBSITE " + url)
+	}
+	return report, agg, nil
 }
 
-// Update Medium Term report for url
-func (o *Orchestrator) UpdateMediumReport(url string) error {
-	err := o.reports[url].MediumTerm.Update(o.aggregators[url].Medium)
-	return err
+// Update report of url over window
+func (o *Orchestrator) UpdateReport(url string, window string) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	report, agg, err := o.reportAndAggregators(url)
+	if err != nil {
+		return err
+	}
+	measures := report.Get(window)
+	aggregator := agg.Get(window)
+	if measures == nil || aggregator == nil {
+		return errors.New("NO WINDOW " + window + " FOR WEBSITE " + url)
+	}
+	report.Certificate = agg.Certificates.Latest()
+	return measures.Update(aggregator)
 }
 
-// Update Long Term report for url
-func (o *Orchestrator) UpdateLongReport(url string) error {
-	err := o.reports[url].LongTerm.Update(o.aggregators[url].Long)
-	return err
+// Get aggregation windows of url
+func (o *Orchestrator) GetWindows(url string) ([]Window, error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	agg, exists := o.aggregators[url]
+	if !exists {
+		return nil, errors.New("NO AGGREGATOR FOR WEBSITE " + url)
+	}
+	return agg.Windows, nil
+}
+
+// Whether url is registered
+func (o *Orchestrator) isRegistered(url string) bool {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	_, registered := o.probes[url]
+	return registered
 }
 
 // Start monitoring for all registered websites
+// Websites unregistered meanwhile are skipped
 func (o *Orchestrator) StartAll() error {
-	pingers, err := o.GetPingers()
-	if err != nil {
-		return err
-	}
-	for _, pinger := range pingers {
-		_, err = o.Start(pinger.Url)
-		if err != nil {
+	for _, url := range o.GetUrls() {
+		_, err := o.Start(url)
+		if err != nil && o.isRegistered(url) {
 			return err
 		}
 	}
-	return err
+	return nil
 }
 
 // Pause monitoring for all registered websites
+// Websites unregistered meanwhile are skipped
 func (o *Orchestrator) PauseAll() error {
-	pingers, err := o.GetPingers()
-	if err != nil {
-		return err
-	}
-	for _, pinger := range pingers {
-		_, err = o.Pause(pinger.Url)
-		if err != nil {
+	for _, url := range o.GetUrls() {
+		_, err := o.Pause(url)
+		if err != nil && o.isRegistered(url) {
 			return err
 		}
 	}
-	return err
+	return nil
 }
 
 // Unregister all websites
+// Websites unregistered meanwhile are skipped
 func (o *Orchestrator) UnregisterAll() error {
-	pingers, err := o.GetPingers()
-	if err != nil {
-		return err
-	}
-	for _, pinger := range pingers {
-		err = o.Unregister(pinger.Url)
-		if err != nil {
+	for _, url := range o.GetUrls() {
+		err := o.Unregister(url)
+		if err != nil && o.isRegistered(url) {
 			return err
 		}
 	}
-	return err
+	return nil
 }
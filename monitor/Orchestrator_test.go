@@ -1,6 +1,11 @@
 package monitor
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 )
@@ -12,12 +17,33 @@ var orchestrator_test *Orchestrator
 func setup() {
 	pipeline_test = make(chan PingLog)
 	alerts_test = make(chan Alert)
-	orchestrator_test = &Orchestrator{
-		pipeline:    pipeline_test,
-		alerts:      alerts_test,
-		pingers:     make(map[string]*Pinger),
-		aggregators: make(map[string]*Aggregators),
-		reports:     make(map[string]*Report),
+	orchestrator_test, _ = NewOrchestrator(OrchestratorOptions{Pipeline: pipeline_test, Alerts: alerts_test})
+}
+
+// Consume the alerts of the test orchestrator until the end of the test
+// Returns a function counting the alerts received so far
+func collectAlerts(t *testing.T) func() int {
+	alerts := alerts_test
+	done := make(chan bool)
+	t.Cleanup(func() { close(done) })
+	count := 0
+	var mutex sync.Mutex
+	go func() {
+		for {
+			select {
+			case <-alerts:
+				mutex.Lock()
+				count++
+				mutex.Unlock()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return count
 	}
 }
 
@@ -30,27 +56,110 @@ func TestGetOrchestrator(t *testing.T) {
 	}
 }
 
+func TestOrchestrator_Independent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	_, err := NewOrchestrator(OrchestratorOptions{Windows: []Window{{"short", 0}}})
+	if err == nil {
+		t.Error("Expected an error for invalid windows")
+	}
+
+	orchestrators := make([]*Orchestrator, 2)
+	runs := make(chan error, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for idx := range orchestrators {
+		o, err := NewOrchestrator(OrchestratorOptions{Windows: []Window{{"1m", time.Minute}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		orchestrators[idx] = o
+	}
+	go func() { runs <- orchestrators[0].Run(ctx) }()
+	go func() { runs <- orchestrators[1].Run(context.Background()) }()
+
+	// Both monitor the same website, with their own probes and aggregators
+	for _, o := range orchestrators {
+		err := o.Register(Website{Url: server.URL, CheckInterval: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = o.Start(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	for idx, o := range orchestrators {
+		agg, err := o.GetAggregator(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count, _ := agg.Get("1m").GetCount(); count == 0 {
+			t.Error("Expected the checks of orchestrator", idx, "to be aggregated")
+		}
+	}
+
+	// Closing one leaves the other running
+	err = orchestrators[1].Close()
+	if err != nil {
+		t.Error("Error while closing orchestrator:", err)
+	}
+	if err = <-runs; err != nil {
+		t.Error("Expected Run to return nil once closed, got", err)
+	}
+	if active, _ := orchestrators[1].IsActive(server.URL); active {
+		t.Error("Expected the probes of a closed orchestrator to be stopped")
+	}
+	if active, _ := orchestrators[0].IsActive(server.URL); !active {
+		t.Error("Expected the other orchestrator to keep running")
+	}
+	if orchestrators[1].Register(Website{Url: "example", CheckInterval: 100}) == nil {
+		t.Error("Registering a website on a closed orchestrator should return a non-nil error")
+	}
+	if _, err = orchestrators[1].Start(server.URL); err == nil {
+		t.Error("Starting a website on a closed orchestrator should return a non-nil error")
+	}
+
+	cancel()
+	if err = <-runs; err != context.Canceled {
+		t.Error("Expected Run to return once its context is cancelled, got", err)
+	}
+	orchestrators[0].Close()
+
+	// Run returns once the pipeline is closed
+	pipeline := make(chan PingLog)
+	o, err := NewOrchestrator(OrchestratorOptions{Pipeline: pipeline})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	go func() { runs <- o.Run(context.Background()) }()
+	close(pipeline)
+	select {
+	case err = <-runs:
+		if err != nil {
+			t.Error("Expected Run to return nil once the pipeline is closed, got", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Run did not return once the pipeline was closed")
+	}
+}
+
 func TestOrchestrator_Register(t *testing.T) {
 	setup()
 	time.Sleep(10 * time.Millisecond)
 	website := Website{Url: "example", CheckInterval: 100}
 
 	// Handle alert emitting
-	messages := make([]Alert, 0)
-	loop := true
-	defer func() { loop = false }()
-	go func() {
-		for loop {
-			messages = append(messages, <-alerts_test)
-		}
-	}()
+	messages := collectAlerts(t)
 	err := orchestrator_test.Register(website)
 	if err != nil {
 		t.Error("Error while registering website:", err)
 	}
 	// Wait for alert to be emitted and caught
 	time.Sleep(10 * time.Millisecond)
-	if len(messages) != 1 {
+	if messages() != 1 {
 		t.Error("Alert was not raised (first register)")
 	}
 	_, ok := orchestrator_test.aggregators[website.Url]
@@ -61,7 +170,7 @@ func TestOrchestrator_Register(t *testing.T) {
 	if !ok {
 		t.Error("Report was not added")
 	}
-	_, ok = orchestrator_test.pingers[website.Url]
+	_, ok = orchestrator_test.probes[website.Url]
 	if !ok {
 		t.Error("Pinger was not added")
 	}
@@ -71,10 +180,19 @@ func TestOrchestrator_Register(t *testing.T) {
 	}
 	// Wait for alert to be emitted and caught
 	time.Sleep(10 * time.Millisecond)
-	if len(messages) != 2 {
+	if messages() != 2 {
 		t.Error("Alert was not raised (second register)")
 	}
 
+	for _, interval := range []int{0, -100} {
+		err = orchestrator_test.Register(Website{Url: "interval", CheckInterval: interval})
+		if err == nil {
+			t.Error("Expected an error for a check interval of", interval)
+		}
+	}
+	if _, err = orchestrator_test.GetWebsite("interval"); err == nil {
+		t.Error("Website with an invalid check interval was registered")
+	}
 }
 
 func TestOrchestrator_Unregister(t *testing.T) {
@@ -82,14 +200,7 @@ func TestOrchestrator_Unregister(t *testing.T) {
 	website := Website{Url: "example", CheckInterval: 100}
 
 	// Handle alert emitting
-	messages := make([]Alert, 0)
-	loop := true
-	defer func() { loop = false }()
-	go func() {
-		for loop {
-			messages = append(messages, <-alerts_test)
-		}
-	}()
+	messages := collectAlerts(t)
 
 	err := orchestrator_test.Register(website)
 	if err != nil {
@@ -101,19 +212,15 @@ func TestOrchestrator_Unregister(t *testing.T) {
 	}
 	// Waiting for pinger to start
 	time.Sleep(10 * time.Millisecond)
-	err = orchestrator_test.Unregister(website.Url)
-	if err == nil {
-		t.Error("Un-registering a website while monitoring should return a non-nil error")
-	}
-	_, err = orchestrator_test.Pause(website.Url)
-	if err != nil {
-		t.Error("Error while pausing website:", err)
-	}
-
+	probe := orchestrator_test.probes[website.Url]
+	// Un-registering a website while monitoring stops it
 	err = orchestrator_test.Unregister(website.Url)
 	if err != nil {
 		t.Error("Error while un-registering website:", err)
 	}
+	if probe.IsRunning() {
+		t.Error("Pinger was not stopped")
+	}
 	_, ok := orchestrator_test.aggregators[website.Url]
 	if ok {
 		t.Error("Aggregators were not deleted")
@@ -122,13 +229,13 @@ func TestOrchestrator_Unregister(t *testing.T) {
 	if ok {
 		t.Error("Report was not deleted")
 	}
-	_, ok = orchestrator_test.pingers[website.Url]
+	_, ok = orchestrator_test.probes[website.Url]
 	if ok {
 		t.Error("Pinger was not deleted")
 	}
 	// Wait for alert to be emitted and caught
 	time.Sleep(10 * time.Millisecond)
-	if len(messages) != 5 {
+	if messages() != 3 {
 		t.Error("Alert was not raised (first unregister)")
 	}
 
@@ -138,7 +245,7 @@ func TestOrchestrator_Unregister(t *testing.T) {
 	}
 	// Wait for alert to be emitted and caught
 	time.Sleep(10 * time.Millisecond)
-	if len(messages) != 6 {
+	if messages() != 4 {
 		t.Error("Alert was not raised (second unregister)")
 	}
 
@@ -150,14 +257,7 @@ func TestOrchestrator_Start(t *testing.T) {
 	website := Website{Url: "example", CheckInterval: 100}
 
 	// Handle alert emitting
-	messages := make([]Alert, 0)
-	loop := true
-	defer func() { loop = false }()
-	go func() {
-		for loop {
-			messages = append(messages, <-alerts_test)
-		}
-	}()
+	messages := collectAlerts(t)
 
 	err := orchestrator_test.Register(website)
 	if err != nil {
@@ -170,10 +270,10 @@ func TestOrchestrator_Start(t *testing.T) {
 	}
 	// Wait for alert to be emitted and caught
 	time.Sleep(10 * time.Millisecond)
-	if len(messages) != 2 {
+	if messages() != 2 {
 		t.Error("Alert was not raised")
 	}
-	if !orchestrator_test.pingers[website.Url].IsRunning {
+	if !orchestrator_test.probes[website.Url].IsRunning() {
 		t.Error("Pinger was not started")
 	}
 	if !orchestrator_test.reports[website.Url].Active {
@@ -183,7 +283,7 @@ func TestOrchestrator_Start(t *testing.T) {
 	// Should not crash
 	orchestrator_test.Start(website.Url)
 
-	if !orchestrator_test.pingers[website.Url].IsRunning {
+	if !orchestrator_test.probes[website.Url].IsRunning() {
 		t.Error("Pinger was not started")
 	}
 	if !orchestrator_test.reports[website.Url].Active {
@@ -198,14 +298,7 @@ func TestOrchestrator_Pause(t *testing.T) {
 	website := Website{Url: "example", CheckInterval: 100}
 
 	// Handle alert emitting
-	messages := make([]Alert, 0)
-	loop := true
-	defer func() { loop = false }()
-	go func() {
-		for loop {
-			messages = append(messages, <-alerts_test)
-		}
-	}()
+	collectAlerts(t)
 
 	err := orchestrator_test.Register(website)
 	if err != nil {
@@ -225,7 +318,7 @@ func TestOrchestrator_Pause(t *testing.T) {
 	// Waiting for pinger to stop
 	time.Sleep(10 * time.Millisecond)
 
-	if orchestrator_test.pingers[website.Url].IsRunning {
+	if orchestrator_test.probes[website.Url].IsRunning() {
 		t.Error("Pinger was not paused")
 	}
 	if orchestrator_test.reports[website.Url].Active {
@@ -233,3 +326,183 @@ func TestOrchestrator_Pause(t *testing.T) {
 	}
 
 }
+
+func TestOrchestrator_Concurrent(t *testing.T) {
+	setup()
+	collectAlerts(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	scheduler, _ := newTestScheduler(t, SchedulerOptions{MaxConcurrency: 4})
+
+	// Logs keep flowing while websites are controlled
+	pipeline, orchestrator := pipeline_test, orchestrator_test
+	var logs sync.WaitGroup
+	logs.Add(1)
+	received := make(chan int, 1)
+	go func() {
+		defer logs.Done()
+		count := 0
+		for log := range pipeline {
+			count++
+			orchestrator.AggLog(log)
+		}
+		received <- count
+	}()
+
+	urls := make([]string, 6)
+	for idx := range urls {
+		urls[idx] = server.URL + "/" + strconv.Itoa(idx)
+	}
+	stop := time.Now().Add(500 * time.Millisecond)
+	var workers sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		workers.Add(1)
+		go func(worker int) {
+			defer workers.Done()
+			for step := worker; time.Now().Before(stop); step++ {
+				url := urls[step%len(urls)]
+				switch step % 7 {
+				case 0:
+					orchestrator.Register(Website{Url: url, CheckInterval: 2})
+				case 1:
+					orchestrator.Start(url)
+				case 2:
+					orchestrator.Pause(url)
+				case 3:
+					orchestrator.Toggle(url)
+				case 4:
+					orchestrator.Unregister(url)
+				case 5:
+					for _, window := range DefaultWindows() {
+						orchestrator.UpdateReport(url, window.Name)
+					}
+					orchestrator.GetSummary(url)
+				case 6:
+					if worker == 0 {
+						orchestrator.SetScheduler(scheduler)
+					}
+					for _, registered := range orchestrator.GetUrls() {
+						orchestrator.IsActive(registered)
+						orchestrator.GetReport(registered)
+					}
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}(worker)
+	}
+	workers.Wait()
+
+	err := orchestrator.UnregisterAll()
+	if err != nil {
+		t.Error("Error while un-registering websites:", err)
+	}
+	if len(orchestrator.GetUrls()) != 0 {
+		t.Error("Websites are still registered:", orchestrator.GetUrls())
+	}
+	// No log is sent once every website is unregistered
+	time.Sleep(50 * time.Millisecond)
+	close(pipeline)
+	logs.Wait()
+	if count := <-received; count == 0 {
+		t.Error("Expected checks while websites were controlled")
+	}
+}
+
+func TestOrchestrator_Toggle(t *testing.T) {
+	setup()
+	collectAlerts(t)
+	website := Website{Url: "example", CheckInterval: 100}
+	err := orchestrator_test.Register(website)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each toggle flips the state once, even when they run concurrently
+	results := make(chan bool, 10)
+	var toggles sync.WaitGroup
+	for idx := 0; idx < 10; idx++ {
+		toggles.Add(1)
+		go func() {
+			defer toggles.Done()
+			active, err := orchestrator_test.Toggle(website.Url)
+			if err != nil {
+				t.Error("Error while toggling website:", err)
+			}
+			results <- active
+		}()
+	}
+	toggles.Wait()
+	close(results)
+	started := 0
+	for active := range results {
+		if active {
+			started++
+		}
+	}
+	if started != 5 {
+		t.Error("Expected half of the toggles to start the website, got", started)
+	}
+	active, _ := orchestrator_test.IsActive(website.Url)
+	if active || orchestrator_test.GetReport(website.Url).Active {
+		t.Error("Expected the website to be paused after an even number of toggles")
+	}
+	orchestrator_test.Unregister(website.Url)
+}
+
+func TestOrchestrator_AllWhileUnregistering(t *testing.T) {
+	o, err := NewOrchestrator(OrchestratorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	for iteration := 0; iteration < 10; iteration++ {
+		urls := make([]string, 0)
+		for idx := 0; idx < 100; idx++ {
+			url := "http://all.test/" + strconv.Itoa(idx)
+			o.Register(Website{Url: url, CheckInterval: 1000})
+			urls = append(urls, url)
+		}
+
+		// Websites unregistered after the snapshot of the urls do not fail the others
+		var unregistering sync.WaitGroup
+		unregistering.Add(1)
+		go func() {
+			defer unregistering.Done()
+			// From the last one, so that the loops below meet urls already unregistered
+			for idx := len(urls) - 1; idx >= 0; idx-- {
+				o.Unregister(urls[idx])
+				time.Sleep(10 * time.Microsecond)
+			}
+		}()
+		for _, all := range []func() error{o.StartAll, o.PauseAll, o.UnregisterAll} {
+			if err := all(); err != nil {
+				t.Fatal("Unexpected error while websites are unregistered:", err)
+			}
+		}
+		unregistering.Wait()
+		if len(o.GetUrls()) != 0 {
+			t.Fatal("Websites are still registered:", o.GetUrls())
+		}
+	}
+}
+
+func TestOrchestrator_RegisterRollback(t *testing.T) {
+	o, err := NewOrchestrator(OrchestratorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	website := Website{Url: "http://rollback.test", CheckInterval: 1000}
+
+	// Left over aggregators make the registration fail after the probe is built
+	o.aggregators[website.Url] = newAggregators(website)
+	if err = o.Register(website); err == nil {
+		t.Fatal("Expected an error for a website which already has aggregators")
+	}
+	if o.isRegistered(website.Url) || o.GetReport(website.Url) != nil {
+		t.Error("Failed registration left the website half registered")
+	}
+	if _, err = o.Start(website.Url); err == nil {
+		t.Error("Expected an error starting a website whose registration failed")
+	}
+}
@@ -0,0 +1,110 @@
+package monitor
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Phases of a check, indexing Phases
+const (
+	PHASE_DNS      = iota // DNS lookup
+	PHASE_CONNECT         // TCP connection
+	PHASE_TLS             // TLS handshake
+	PHASE_TTFB            // From the request being written to the first byte of the response
+	PHASE_TRANSFER        // From the first byte of the response to the end of its body
+	PHASE_COUNT
+)
+
+// Names of the phases, in the order of their indexes
+var PhaseNames = [PHASE_COUNT]string{"dns", "connect", "tls", "ttfb", "transfer"}
+
+// Bodies not needed by assertions are read up to this size to time their transfer
+const MAX_DRAINED_BODY_SIZE = 10 * 1024 * 1024
+
+// Duration of each phase of a check, 0 for phases which did not happen, eg TLS for http websites
+type Phases [PHASE_COUNT]time.Duration
+
+func validatePhase(phase int) error {
+	if phase < 0 || phase >= PHASE_COUNT {
+		return errors.New("INVALID PHASE " + strconv.Itoa(phase))
+	}
+	return nil
+}
+
+// Timestamps of the phases of a request, and certificate presented during its TLS handshake
+// Callbacks of a trace can run after the request is abandoned, hence the mutex
+type phaseTrace struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wrote        time.Time
+	firstByte    time.Time
+	cert         *Certificate
+	mutex        sync.Mutex
+}
+
+func newPhaseTrace(start time.Time) *phaseTrace {
+	return &phaseTrace{start: start}
+}
+
+// Client trace recording the phases of a request
+func (t *phaseTrace) clientTrace() *httptrace.ClientTrace {
+	record := func(timestamp *time.Time) {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		*timestamp = time.Now()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { record(&t.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { record(&t.dnsDone) },
+		ConnectStart:      func(string, string) { record(&t.connectStart) },
+		ConnectDone:       func(string, string, error) { record(&t.connectDone) },
+		TLSHandshakeStart: func() { record(&t.tlsStart) },
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			cert := newCertificate(state, err, t.start)
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.tlsDone = time.Now()
+			t.cert = cert
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { record(&t.wrote) },
+		GotFirstResponseByte: func() { record(&t.firstByte) },
+	}
+}
+
+// Phases recorded so far, end being the end of the body transfer, or zero if it was not read
+func (t *phaseTrace) phases(end time.Time) Phases {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var phases Phases
+	phases[PHASE_DNS] = elapsed(t.dnsStart, t.dnsDone)
+	phases[PHASE_CONNECT] = elapsed(t.connectStart, t.connectDone)
+	phases[PHASE_TLS] = elapsed(t.tlsStart, t.tlsDone)
+	phases[PHASE_TTFB] = elapsed(t.wrote, t.firstByte)
+	phases[PHASE_TRANSFER] = elapsed(t.firstByte, end)
+	return phases
+}
+
+// Certificate presented during the TLS handshake, nil if there was none
+func (t *phaseTrace) certificate() *Certificate {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.cert
+}
+
+// Time between start and end, 0 if either did not happen
+func elapsed(start time.Time, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
@@ -0,0 +1,50 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPhases_Request(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(strings.Repeat("body", 1024)))
+	}))
+	defer server.Close()
+
+	checker := newTLSChecker(server, "")
+	log := checker.Check(context.Background())
+	if log.Error != nil {
+		t.Fatal("Unexpected error:", log.Error)
+	}
+	t.Log(log.Phases)
+	if log.Phases[PHASE_DNS] != 0 {
+		t.Error("Expected no DNS lookup for an IP address, got", log.Phases[PHASE_DNS])
+	}
+	if log.Phases[PHASE_CONNECT] <= 0 || log.Phases[PHASE_TLS] <= 0 {
+		t.Error("Expected connection and TLS handshake durations, got", log.Phases)
+	}
+	if log.Phases[PHASE_TTFB] < 30*time.Millisecond {
+		t.Error("Expected a time to first byte of at least 30 ms, got", log.Phases[PHASE_TTFB])
+	}
+	if log.Phases[PHASE_TRANSFER] < 20*time.Millisecond {
+		t.Error("Expected the body transfer to take at least 20 ms, got", log.Phases[PHASE_TRANSFER])
+	}
+}
+
+func TestPhases_Elapsed(t *testing.T) {
+	now := time.Now()
+	if elapsed(time.Time{}, now) != 0 || elapsed(now, time.Time{}) != 0 {
+		t.Error("Phases which did not happen should last 0")
+	}
+	if elapsed(now, now.Add(time.Second)) != time.Second {
+		t.Error("Unexpected phase duration")
+	}
+}
@@ -1,16 +1,36 @@
 package monitor
 
 import (
-	"net/http"
+	"context"
+	"errors"
+	"sync"
 	"time"
 )
 
+// Delay before retrying an unsuccessful attempt, when websites do not set theirs
+const DEFAULT_RETRY_DELAY = 200 * time.Millisecond
+
+// Checks a website periodically with a Checker, the Probe of the built-in check types
+// Unsuccessful attempts are retried within a check, and unsuccessful checks confirmed, see Website
 type Pinger struct {
-	out        chan<- PingLog
-	Url        string
-	Interval   int
-	IsRunning  bool
-	httpClient *http.Client
+	out          chan<- PingLog
+	Url          string
+	Interval     int
+	checker      Checker
+	retries      int
+	retryDelay   time.Duration
+	confirmAfter int
+	consecutive  int                // Consecutive unsuccessful checks so far
+	scheduler    *Scheduler         // Runs the checks when set, instead of a loop of the pinger
+	ctx          context.Context    // Context of the checks while running, nil when stopped
+	cancel       context.CancelFunc // Cancels ctx
+	done         chan bool          // Closed once the loop returned, nil with a scheduler
+	mutex        sync.Mutex
+}
+
+// Performs a single check of a website, returning early once ctx is cancelled
+type Checker interface {
+	Check(ctx context.Context) PingLog
 }
 
 type PingLog struct {
@@ -19,52 +39,169 @@ type PingLog struct {
 	Error        error
 	Status       int
 	ResponseTime time.Duration
+	Asserted     bool         // Response went through the website's assertions
+	Assertion    error        // First failed assertion, if any
+	Certificate  *Certificate // Certificate presented by https websites, if the TLS handshake went that far
+	Phases       Phases       // Duration of each phase of the request
+	Stdout       string       // Beginning of the output of exec checks, see MAX_OUTPUT_SNIPPET
+	Stderr       string
+	Attempts     int      // Attempts made, the log describing the last one
+	Retried      []string // Failure of each attempt retried, see Failure
+	Unconfirmed  bool     // Unsuccessful, but not for enough consecutive checks to count as down
+}
+
+// Whether the check counts as available
+// Logs which did not go through assertions are available on status 200 only
+func (l *PingLog) IsAvailable() bool {
+	if l.Error != nil || l.Assertion != nil {
+		return false
+	}
+	return l.Asserted || l.Status == 200
+}
+
+// Whether the check counts as up, unconfirmed unsuccessful checks included
+func (l *PingLog) IsUp() bool {
+	return l.IsAvailable() || l.Unconfirmed
 }
 
-func NewPinger(outChan chan<- PingLog, website Website) Pinger {
-	tr := &http.Transport{
-		DisableKeepAlives:     true,
-		ResponseHeaderTimeout: time.Duration(website.CheckInterval) * time.Millisecond,
+// Probe checking website every check interval with checker
+func NewPinger(outChan chan<- PingLog, website Website, checker Checker) *Pinger {
+	retryDelay := website.RetryDelay
+	if retryDelay <= 0 {
+		retryDelay = DEFAULT_RETRY_DELAY
 	}
-	client := &http.Client{Transport: tr}
-	return Pinger{
-		out:        outChan,
-		Url:        website.Url,
-		Interval:   website.CheckInterval,
-		IsRunning:  false,
-		httpClient: client,
+	return &Pinger{
+		out:          outChan,
+		Url:          website.Url,
+		Interval:     website.CheckInterval,
+		checker:      checker,
+		retries:      website.Retries,
+		retryDelay:   retryDelay,
+		confirmAfter: website.ConfirmAfter,
+	}
+}
+
+// Check retries and confirmation settings of website are not negative
+func validateRetries(website Website) error {
+	if website.Retries < 0 || website.RetryDelay < 0 || website.ConfirmAfter < 0 {
+		return errors.New("RETRIES, RETRY DELAY AND CONFIRMATION OF WEBSITE " + website.Url + " MUST NOT BE NEGATIVE")
+	}
+	return nil
+}
+
+// Timeout of the checks of website, its check interval if it has none
+func checkTimeout(website Website) time.Duration {
+	if website.Timeout > 0 {
+		return website.Timeout
+	}
+	return time.Duration(website.CheckInterval) * time.Millisecond
+}
+
+// Check every interval in the background, until ctx is cancelled or Stop is called
+func (p *Pinger) Start(ctx context.Context) {
+	if p.IsRunning() {
+		return
+	}
+	// Release the loop of a previous run, whose context was cancelled
+	p.Stop()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	if p.scheduler != nil {
+		p.scheduler.add(p, p.ctx)
+		return
 	}
+	p.done = make(chan bool)
+	go p.loop(p.ctx, p.done)
 }
 
-func (p *Pinger) Start() {
-	p.IsRunning = true
+func (p *Pinger) loop(ctx context.Context, done chan bool) {
+	defer close(done)
 	tick := time.NewTicker(time.Duration(p.Interval) * time.Millisecond)
+	defer tick.Stop()
 
-	for p.IsRunning {
-		<-tick.C
-		startTime := time.Now()
-		res, err := p.httpClient.Get(p.Url)
-		if err != nil {
-			p.out <- PingLog{
-				startTime,
-				p.Url,
-				err,
-				0,
-				time.Now().Sub(startTime),
-			}
-		} else {
-			p.out <- PingLog{
-				startTime,
-				p.Url,
-				nil,
-				res.StatusCode,
-				time.Now().Sub(startTime),
-			}
-			res.Body.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
 		}
+		p.send(ctx, p.Check(ctx))
+	}
+}
+
+// Send log to the pipeline, unless ctx was cancelled in the meantime
+// Logs of cancelled checks are dropped, they tell nothing about the website
+func (p *Pinger) send(ctx context.Context, log PingLog) {
+	if ctx.Err() != nil {
+		return
+	}
+	select {
+	case p.out <- log:
+	case <-ctx.Done():
+	}
+}
+
+// Stop checking, cancelling the running check, and wait for it to return
+// No log is sent once Stop returns
+func (p *Pinger) Stop() {
+	p.mutex.Lock()
+	cancel, done := p.cancel, p.done
+	p.ctx, p.cancel, p.done = nil, nil, nil
+	p.mutex.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	if p.scheduler != nil {
+		done = p.scheduler.remove(p)
+	}
+	if done != nil {
+		<-done
+	}
+}
+
+// Run the checks with scheduler, before the pinger is started
+func (p *Pinger) useScheduler(scheduler *Scheduler) {
+	p.scheduler = scheduler
+}
+
+// Perform a single check, retrying unsuccessful attempts until ctx is cancelled
+// Checks must not run concurrently, as consecutive unsuccessful checks are counted
+func (p *Pinger) Check(ctx context.Context) PingLog {
+	log := p.checker.Check(ctx)
+	log.Attempts = 1
+	var retried []string
+	for !log.IsAvailable() && log.Attempts <= p.retries {
+		select {
+		case <-time.After(p.retryDelay):
+		case <-ctx.Done():
+			log.Retried = retried
+			return log
+		}
+		retried = append(retried, log.Failure())
+		attempts := log.Attempts
+		log = p.checker.Check(ctx)
+		log.Attempts = attempts + 1
+	}
+	log.Retried = retried
+	if ctx.Err() != nil {
+		// The log of a cancelled check is dropped, it does not count as unsuccessful
+		return log
+	}
+
+	if log.IsAvailable() {
+		p.consecutive = 0
+	} else {
+		p.consecutive++
+		log.Unconfirmed = p.consecutive < p.confirmAfter
 	}
+	return log
 }
 
-func (p *Pinger) Pause() {
-	p.IsRunning = false
+func (p *Pinger) IsRunning() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.ctx != nil && p.ctx.Err() == nil
 }
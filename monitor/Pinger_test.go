@@ -0,0 +1,210 @@
+package monitor
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPinger_Request(t *testing.T) {
+	var method, auth, host, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		auth = r.Header.Get("Authorization")
+		host = r.Host
+		content, _ := ioutil.ReadAll(r.Body)
+		body = string(content)
+		w.WriteHeader(201)
+	}))
+	defer server.Close()
+
+	website := Website{
+		Url:           server.URL,
+		CheckInterval: 100,
+		Method:        "post",
+		Headers: map[string]string{
+			"Authorization": "Bearer token",
+			"Host":          "internal.example.com",
+		},
+		Body: `{"ping":true}`,
+	}
+	checker := newHTTPChecker(website)
+	log := checker.Check(context.Background())
+	if log.Error != nil {
+		t.Fatal("Unexpected error:", log.Error)
+	}
+	if log.Status != 201 {
+		t.Error("Status does not match expectation:", log.Status)
+	}
+	if method != "POST" {
+		t.Error("Method does not match expectation:", method)
+	}
+	if auth != "Bearer token" {
+		t.Error("Authorization header does not match expectation:", auth)
+	}
+	if host != "internal.example.com" {
+		t.Error("Host does not match expectation:", host)
+	}
+	if body != `{"ping":true}` {
+		t.Error("Body does not match expectation:", body)
+	}
+}
+
+func TestPinger_DefaultMethod(t *testing.T) {
+	var method string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+	}))
+	defer server.Close()
+
+	checker := newHTTPChecker(Website{Url: server.URL, CheckInterval: 100})
+	log := checker.Check(context.Background())
+	if log.Error != nil {
+		t.Fatal("Unexpected error:", log.Error)
+	}
+	if method != "GET" {
+		t.Error("Method does not match expectation:", method)
+	}
+}
+
+func TestPinger_Timeout(t *testing.T) {
+	release := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	website := Website{Url: server.URL, CheckInterval: 5000, Timeout: 50 * time.Millisecond}
+	checker := newHTTPChecker(website)
+	start := time.Now()
+	log := checker.Check(context.Background())
+	if log.Error == nil {
+		t.Error("Expected the check to time out")
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Expected the timeout to override the check interval, took", time.Since(start))
+	}
+}
+
+// Checker returning its logs in turn, failing with a timeout once they run out
+type scriptedChecker struct {
+	logs  []PingLog
+	calls int
+}
+
+func (s *scriptedChecker) Check(ctx context.Context) PingLog {
+	s.calls++
+	if len(s.logs) == 0 {
+		return PingLog{Time: time.Now(), Error: context.DeadlineExceeded}
+	}
+	log := s.logs[0]
+	s.logs = s.logs[1:]
+	log.Time = time.Now()
+	return log
+}
+
+func TestPinger_Retries(t *testing.T) {
+	refused := PingLog{Error: syscall.ECONNREFUSED}
+	available := PingLog{Status: 200}
+	checker := &scriptedChecker{logs: []PingLog{refused, refused, available}}
+	pinger := NewPinger(nil, Website{Url: "example", CheckInterval: 1000, Retries: 2, RetryDelay: time.Millisecond}, checker)
+
+	log := pinger.Check(context.Background())
+	if !log.IsAvailable() || log.Attempts != 3 || checker.calls != 3 {
+		t.Error("Expected the third attempt to succeed, got", log.Attempts, log.Error)
+	}
+	if len(log.Retried) != 2 || log.Retried[0] != FAILURE_REFUSED {
+		t.Error("Expected the retried failures to be recorded, got", log.Retried)
+	}
+
+	// Retries run out
+	log = pinger.Check(context.Background())
+	if log.IsAvailable() || log.Attempts != 3 || log.Failure() != FAILURE_TIMEOUT {
+		t.Error("Expected the check to fail after 3 attempts, got", log.Attempts, log.Error)
+	}
+
+	// Available checks are not retried
+	checker.logs = []PingLog{available}
+	checker.calls = 0
+	log = pinger.Check(context.Background())
+	if log.Attempts != 1 || checker.calls != 1 || log.Retried != nil {
+		t.Error("Expected a single attempt, got", log.Attempts, log.Retried)
+	}
+}
+
+func TestPinger_Confirm(t *testing.T) {
+	refused := PingLog{Error: syscall.ECONNREFUSED}
+	available := PingLog{Status: 200}
+	checker := &scriptedChecker{logs: []PingLog{refused, refused, available, refused, refused, refused, refused}}
+	pinger := NewPinger(nil, Website{Url: "example", CheckInterval: 1000, ConfirmAfter: 3}, checker)
+
+	expected := []bool{true, true, false, true, true, false, false}
+	for idx, unconfirmed := range expected {
+		log := pinger.Check(context.Background())
+		if log.Unconfirmed != unconfirmed {
+			t.Error("Check", idx, "expected unconfirmed", unconfirmed, "got", log.Unconfirmed)
+		}
+		if log.IsUp() != (unconfirmed || log.IsAvailable()) {
+			t.Error("Check", idx, "unexpectedly up", log.IsUp())
+		}
+	}
+}
+
+func TestPinger_Stop(t *testing.T) {
+	requested := make(chan bool, 10)
+	cancelled := make(chan bool, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- true
+		<-r.Context().Done()
+		cancelled <- true
+	}))
+	defer server.Close()
+	scheduler, _ := newTestScheduler(t, SchedulerOptions{})
+
+	for _, scheduled := range []bool{false, true} {
+		out := make(chan PingLog)
+		logs := make(chan int)
+		go func() {
+			count := 0
+			for range out {
+				count++
+			}
+			logs <- count
+		}()
+		website := Website{Url: server.URL, CheckInterval: 20, Timeout: 10 * time.Second}
+		pinger := NewPinger(out, website, newHTTPChecker(website))
+		if scheduled {
+			pinger.useScheduler(scheduler)
+		}
+		pinger.Start(context.Background())
+		select {
+		case <-requested:
+		case <-time.After(time.Second):
+			t.Fatal("Website was not checked, scheduled:", scheduled)
+		}
+
+		start := time.Now()
+		pinger.Stop()
+		if time.Since(start) > time.Second || pinger.IsRunning() {
+			t.Error("Expected Stop to cancel the running check, scheduled:", scheduled)
+		}
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Error("Request was not cancelled, scheduled:", scheduled)
+		}
+		time.Sleep(100 * time.Millisecond)
+		close(out)
+		if count := <-logs; count != 0 {
+			t.Error("Expected no log of the cancelled check, got", count, "scheduled:", scheduled)
+		}
+		if len(requested) != 0 {
+			t.Error("Website was checked after Stop returned, scheduled:", scheduled)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Monitors a website, sending the log of each check to the pipeline while it runs
+// No log may be sent once Stop returned
+// New check types implement it, or Checker and use NewPinger, and are added with RegisterProbe
+type Probe interface {
+	Start(ctx context.Context)         // Check the website periodically in the background, until ctx is cancelled or Stop is called
+	Stop()                             // Stop checking the website, cancelling the running check, and wait for it to return
+	Check(ctx context.Context) PingLog // Perform a single check, aborted when ctx is cancelled
+	IsRunning() bool
+}
+
+// Probes whose checks can be run by a Scheduler
+type schedulable interface {
+	useScheduler(scheduler *Scheduler)
+}
+
+// Builds the probe of website, sending its logs to out
+// Errors reject the website on registration, eg invalid urls
+type ProbeFactory func(out chan<- PingLog, website Website) (Probe, error)
+
+// Types of the built-in probes
+const (
+	PROBE_HTTP = "http"
+	PROBE_TCP  = "tcp"
+	PROBE_DNS  = "dns"
+	PROBE_EXEC = "exec"
+)
+
+var (
+	probeFactories = map[string]ProbeFactory{
+		PROBE_HTTP: newHTTPProbe,
+		"https":    newHTTPProbe,
+		PROBE_TCP:  newTCPProbe,
+		PROBE_DNS:  newDNSProbe,
+		PROBE_EXEC: newExecProbe,
+	}
+	probeMutex sync.RWMutex
+)
+
+// Add a probe type, selected by websites of that type or whose url has that scheme
+func RegisterProbe(kind string, factory ProbeFactory) error {
+	kind = strings.ToLower(kind)
+	if kind == "" || factory == nil {
+		return errors.New("PROBE TYPE AND FACTORY ARE REQUIRED")
+	}
+	probeMutex.Lock()
+	defer probeMutex.Unlock()
+	if _, exists := probeFactories[kind]; exists {
+		return errors.New("PROBE TYPE " + kind + " ALREADY REGISTERED")
+	}
+	probeFactories[kind] = factory
+	return nil
+}
+
+// Registered probe types, sorted
+func ProbeTypes() []string {
+	probeMutex.RLock()
+	defer probeMutex.RUnlock()
+	kinds := make([]string, 0, len(probeFactories))
+	for kind := range probeFactories {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Build the probe of website, selected by its type, or else the scheme of its url, http by default
+func NewProbe(out chan<- PingLog, website Website) (Probe, error) {
+	kind := ProbeType(website)
+	probeMutex.RLock()
+	factory, exists := probeFactories[kind]
+	probeMutex.RUnlock()
+	if !exists {
+		return nil, errors.New("NO PROBE FOR TYPE " + kind + " OF WEBSITE " + website.Url)
+	}
+	return factory(out, website)
+}
+
+// Type of the probe of website, its type if set, or else the scheme of its url, http by default
+func ProbeType(website Website) string {
+	if website.Type != "" {
+		return strings.ToLower(website.Type)
+	}
+	idx := strings.Index(website.Url, "://")
+	if idx <= 0 {
+		return PROBE_HTTP
+	}
+	return strings.ToLower(website.Url[:idx])
+}
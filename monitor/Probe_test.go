@@ -0,0 +1,139 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Custom probe reporting a queue depth as its response time
+type queueProbe struct {
+	out     chan<- PingLog
+	url     string
+	depth   time.Duration
+	cancel  context.CancelFunc
+	stopped chan bool
+}
+
+func (q *queueProbe) Start(ctx context.Context) {
+	ctx, q.cancel = context.WithCancel(ctx)
+	q.stopped = make(chan bool)
+	go func() {
+		defer close(q.stopped)
+		select {
+		case q.out <- q.Check(ctx):
+		case <-ctx.Done():
+		}
+		<-ctx.Done()
+	}()
+}
+
+func (q *queueProbe) Stop() {
+	if q.cancel != nil {
+		q.cancel()
+		<-q.stopped
+		q.cancel = nil
+	}
+}
+
+func (q *queueProbe) Check(ctx context.Context) PingLog {
+	return PingLog{Time: time.Now(), Website: q.url, ResponseTime: q.depth, Asserted: true}
+}
+
+func (q *queueProbe) IsRunning() bool {
+	return q.cancel != nil
+}
+
+// Register the queue probe type for the duration of a test
+func registerQueueProbe(t *testing.T) {
+	err := RegisterProbe("Queue", func(out chan<- PingLog, website Website) (Probe, error) {
+		return &queueProbe{out: out, url: website.Url, depth: 42 * time.Millisecond}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		probeMutex.Lock()
+		defer probeMutex.Unlock()
+		delete(probeFactories, "queue")
+	})
+}
+
+func TestProbe_Registry(t *testing.T) {
+	registerQueueProbe(t)
+	err := RegisterProbe("queue", newHTTPProbe)
+	if err == nil {
+		t.Error("Expected an error registering a probe type twice")
+	}
+	kinds := ProbeTypes()
+	if len(kinds) != 6 || kinds[0] != PROBE_DNS || kinds[4] != "queue" {
+		t.Error("Unexpected probe types", kinds)
+	}
+
+	probes := []struct {
+		website  Website
+		expected string
+	}{
+		{Website{Url: "example.com"}, "*monitor.Pinger"},
+		{Website{Url: "queue://orders"}, "*monitor.queueProbe"},
+		{Website{Url: "https://example.com/depth", Type: "QUEUE"}, "*monitor.queueProbe"},
+	}
+	for _, c := range probes {
+		c.website.CheckInterval = 1000
+		probe, err := NewProbe(nil, c.website)
+		if err != nil {
+			t.Error("Unexpected error for", c.website.Url, err)
+			continue
+		}
+		if fmt.Sprintf("%T", probe) != c.expected {
+			t.Errorf("Expected a %s for %s, got %T", c.expected, c.website.Url, probe)
+		}
+	}
+
+	_, err = NewProbe(nil, Website{Url: "amqp://broker", CheckInterval: 1000})
+	if err == nil {
+		t.Error("Expected an error for a scheme without probe")
+	}
+}
+
+func TestProbe_Orchestrator(t *testing.T) {
+	registerQueueProbe(t)
+	setup()
+	collectAlerts(t)
+	pipeline, orchestrator := pipeline_test, orchestrator_test
+	go func() {
+		for log := range pipeline {
+			orchestrator.AggLog(log)
+		}
+	}()
+
+	website := Website{Url: "queue://orders", CheckInterval: 100}
+	err := orchestrator_test.Register(website)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = orchestrator_test.Start(website.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	probe, err := orchestrator_test.GetProbe(website.Url)
+	if err != nil || !probe.IsRunning() {
+		t.Fatal("Expected the probe to be running", err)
+	}
+	agg, _ := orchestrator_test.GetAggregator(website.Url)
+	max, _ := agg.Aggregators[0].GetMaxResTime()
+	if max != 42 {
+		t.Error("Expected the check of the custom probe to be aggregated")
+	}
+
+	_, err = orchestrator_test.Pause(website.Url)
+	if err != nil || probe.IsRunning() {
+		t.Error("Expected the probe to be stopped", err)
+	}
+	err = orchestrator_test.Unregister(website.Url)
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,118 @@
+package monitor
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// Urls of the websites affected by a reload
+type ReloadSummary struct {
+	Added   []string
+	Removed []string
+	Changed []string // Re-registered, their measures are reset
+}
+
+// Whether the reload changed any website
+func (s ReloadSummary) IsEmpty() bool {
+	return len(s.Added) == 0 && len(s.Removed) == 0 && len(s.Changed) == 0
+}
+
+// Synchronize registered websites with websites, previous being the websites of the last (re)load
+// Websites of previous missing from websites are unregistered, new ones registered and started,
+// and changed ones re-registered, and restarted if they were running.
+// Unchanged websites keep their aggregators, and websites registered otherwise, eg through the API, are left as is
+func (o *Orchestrator) Reload(previous []Website, websites []Website) (ReloadSummary, error) {
+	summary := ReloadSummary{}
+	wanted := make(map[string]Website)
+	for _, website := range websites {
+		wanted[website.Url] = website
+	}
+	managed := make(map[string]bool)
+	for _, website := range previous {
+		managed[website.Url] = true
+	}
+
+	for _, url := range o.GetUrls() {
+		website, found := wanted[url]
+		if !found && !managed[url] {
+			continue
+		}
+		registered, err := o.GetWebsite(url)
+		if err != nil {
+			// Unregistered in the meantime
+			continue
+		}
+		if found && sameWebsite(registered, website) {
+			continue
+		}
+		running, err := o.IsActive(url)
+		if err != nil {
+			return summary, err
+		}
+		_, err = o.Pause(url)
+		if err != nil {
+			return summary, err
+		}
+		err = o.Unregister(url)
+		if err != nil {
+			return summary, err
+		}
+		if !found {
+			summary.Removed = append(summary.Removed, url)
+			continue
+		}
+		err = o.Register(website)
+		if err != nil {
+			return summary, err
+		}
+		summary.Changed = append(summary.Changed, url)
+		if running {
+			_, err = o.Start(url)
+			if err != nil {
+				return summary, err
+			}
+		}
+	}
+
+	for _, website := range websites {
+		if _, err := o.GetWebsite(website.Url); err == nil {
+			continue
+		}
+		err := o.Register(website)
+		if err != nil {
+			return summary, err
+		}
+		summary.Added = append(summary.Added, website.Url)
+		_, err = o.Start(website.Url)
+		if err != nil {
+			return summary, err
+		}
+	}
+	sort.Strings(summary.Added)
+	return summary, nil
+}
+
+// Whether two websites are monitored the same way
+func sameWebsite(a Website, b Website) bool {
+	if !sameRegexps(a.Assertions.Matches, b.Assertions.Matches) ||
+		!sameRegexps(a.Assertions.NotMatches, b.Assertions.NotMatches) {
+		return false
+	}
+	// Compiled regular expressions are compared on their source only
+	a.Assertions.Matches, a.Assertions.NotMatches = nil, nil
+	b.Assertions.Matches, b.Assertions.NotMatches = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+func sameRegexps(a []*regexp.Regexp, b []*regexp.Regexp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx].String() != b[idx].String() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestOrchestrator_Reload(t *testing.T) {
+	setup()
+	collectAlerts(t)
+
+	kept := Website{Url: "http://kept", CheckInterval: 10000}
+	changed := Website{Url: "http://changed", CheckInterval: 10000}
+	removed := Website{Url: "http://removed", CheckInterval: 10000}
+	previous := []Website{kept, changed, removed}
+	summary, err := orchestrator_test.Reload(nil, previous)
+	if err != nil {
+		t.Fatal("Error while loading websites:", err)
+	}
+	if len(summary.Added) != 3 || len(summary.Removed) != 0 || len(summary.Changed) != 0 {
+		t.Error("Expected 3 added websites, got", summary)
+	}
+	// Registered through the API, out of the config
+	err = orchestrator_test.Register(Website{Url: "http://api", CheckInterval: 10000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keptAggregators := orchestrator_test.aggregators[kept.Url]
+
+	changed.CheckInterval = 5000
+	added := Website{Url: "http://added", CheckInterval: 10000}
+	summary, err = orchestrator_test.Reload(previous, []Website{kept, changed, added})
+	if err != nil {
+		t.Fatal("Error while reloading websites:", err)
+	}
+	if len(summary.Added) != 1 || summary.Added[0] != added.Url {
+		t.Error("Expected", added.Url, "to be added, got", summary.Added)
+	}
+	if len(summary.Removed) != 1 || summary.Removed[0] != removed.Url {
+		t.Error("Expected", removed.Url, "to be removed, got", summary.Removed)
+	}
+	if len(summary.Changed) != 1 || summary.Changed[0] != changed.Url {
+		t.Error("Expected", changed.Url, "to be changed, got", summary.Changed)
+	}
+	if orchestrator_test.aggregators[kept.Url] != keptAggregators {
+		t.Error("Aggregators of an unchanged website were replaced")
+	}
+	if orchestrator_test.probes[changed.Url].(*Pinger).Interval != 5000 {
+		t.Error("Changed website was not re-registered")
+	}
+	if !orchestrator_test.reports[changed.Url].Active || !orchestrator_test.reports[added.Url].Active {
+		t.Error("Changed and added websites should be running")
+	}
+	if _, err = orchestrator_test.GetWebsite("http://api"); err != nil {
+		t.Error("Website registered out of the config should be left as is")
+	}
+	if _, err = orchestrator_test.GetWebsite(removed.Url); err == nil {
+		t.Error("Removed website is still registered")
+	}
+
+	summary, err = orchestrator_test.Reload([]Website{kept, changed, added}, []Website{kept, changed, added})
+	if err != nil || !summary.IsEmpty() {
+		t.Error("Expected an empty reload, got", summary, err)
+	}
+	orchestrator_test.PauseAll()
+}
+
+func TestReload_SameWebsite(t *testing.T) {
+	a := Website{
+		Url:           "http://example",
+		CheckInterval: 1000,
+		Headers:       map[string]string{"Accept": "text/html"},
+		Assertions:    Assertions{Matches: []*regexp.Regexp{regexp.MustCompile(`\d+`)}},
+	}
+	b := a
+	b.Headers = map[string]string{"Accept": "text/html"}
+	b.Assertions.Matches = []*regexp.Regexp{regexp.MustCompile(`\d+`)}
+	if !sameWebsite(a, b) {
+		t.Error("Websites with the same definition should be the same")
+	}
+	b.Assertions.Matches = []*regexp.Regexp{regexp.MustCompile(`\w+`)}
+	if sameWebsite(a, b) {
+		t.Error("Websites with different regular expressions should differ")
+	}
+	b = a
+	b.Headers = map[string]string{"Accept": "application/json"}
+	if sameWebsite(a, b) {
+		t.Error("Websites with different headers should differ")
+	}
+}
@@ -3,105 +3,192 @@ package monitor
 import (
 	"fmt"
 	"math"
+	"strings"
 )
 
 type Measures struct {
+	Window           string // Name of the window
 	Period           string
 	AvgRes           float32
 	MaxRes           float32
+	P50Res           float32
+	P90Res           float32
+	P95Res           float32
+	P99Res           float32
 	Share2XX         float32
 	Share5XX         float32
 	Share3XX         float32
 	Share4XX         float32
 	Availability     float32
 	UnsuccessfulRate float32
+	AssertionRate    float32        // Share of responses failing an assertion
+	Failures         map[string]int // Number of unsuccessful checks per category, assertion failures included
+	Unconfirmed      int            // Unsuccessful checks not confirmed yet, counted as available
+	Retries          int            // Attempts retried within checks
+	Phases           []PhaseMeasures
+}
+
+// Average and maximum duration of a phase of successful requests, in ms
+type PhaseMeasures struct {
+	Phase string
+	Avg   float32
+	Max   float32
 }
 
 type Report struct {
+	Name          string
 	Url           string
+	Tags          []string
 	Active        bool
 	CheckInterval int
-	ShortTerm     Measures
-	MediumTerm    Measures
-	LongTerm      Measures
+	Measures      []Measures   // One per window, in the order of the website's windows
+	Certificate   *Certificate // Latest TLS certificate, nil for http websites
 }
 
 func NewReport(website Website) (*Report, error) {
+	windows := website.Windows
+	if len(windows) == 0 {
+		windows = DefaultWindows()
+	}
 	report := Report{
 		Active:        false,
+		Name:          website.Name,
 		Url:           website.Url,
+		Tags:          website.Tags,
 		CheckInterval: website.CheckInterval,
-		ShortTerm: Measures{
-			Period:           "Past 2 min",
-			AvgRes:           -1.,
-			MaxRes:           -1.,
-			Share2XX:         -1.,
-			Share5XX:         -1.,
-			Share3XX:         -1.,
-			Share4XX:         -1.,
-			Availability:     -1.,
-			UnsuccessfulRate: -1.,
-		},
-		MediumTerm: Measures{
-			Period:           "Past 10 min",
-			AvgRes:           -1.,
-			MaxRes:           -1.,
-			Share2XX:         -1.,
-			Share5XX:         -1.,
-			Share3XX:         -1.,
-			Share4XX:         -1.,
-			Availability:     -1.,
-			UnsuccessfulRate: -1.,
-		},
-		LongTerm: Measures{
-			Period:           "Past 1 hour",
-			AvgRes:           -1.,
-			MaxRes:           -1.,
-			Share2XX:         -1.,
-			Share5XX:         -1.,
-			Share3XX:         -1.,
-			Share4XX:         -1.,
-			Availability:     -1.,
-			UnsuccessfulRate: -1.,
-		},
+		Measures:      make([]Measures, len(windows)),
+	}
+	for idx, window := range windows {
+		report.Measures[idx] = newMeasures(window)
 	}
 	return &report, nil
 }
 
+// Measures with every value still being collected
+func newMeasures(window Window) Measures {
+	return Measures{
+		Window:           window.Name,
+		Period:           window.Period(),
+		AvgRes:           -1.,
+		MaxRes:           -1.,
+		P50Res:           -1.,
+		P90Res:           -1.,
+		P95Res:           -1.,
+		P99Res:           -1.,
+		Share2XX:         -1.,
+		Share5XX:         -1.,
+		Share3XX:         -1.,
+		Share4XX:         -1.,
+		Availability:     -1.,
+		UnsuccessfulRate: -1.,
+		AssertionRate:    -1.,
+		Phases:           newPhaseMeasures(),
+	}
+}
+
+// Phase measures with every value still being collected
+func newPhaseMeasures() []PhaseMeasures {
+	phases := make([]PhaseMeasures, PHASE_COUNT)
+	for phase, name := range PhaseNames {
+		phases[phase] = PhaseMeasures{Phase: name, Avg: -1., Max: -1.}
+	}
+	return phases
+}
+
+// Copy of the report, sharing no measures with it
+func (r *Report) copy() *Report {
+	out := *r
+	out.Measures = make([]Measures, len(r.Measures))
+	for idx, measures := range r.Measures {
+		measures.Phases = append([]PhaseMeasures(nil), measures.Phases...)
+		out.Measures[idx] = measures
+	}
+	return &out
+}
+
+// Get the measures over window, nil if there are none
+func (r *Report) Get(window string) *Measures {
+	for idx := range r.Measures {
+		if r.Measures[idx].Window == window {
+			return &r.Measures[idx]
+		}
+	}
+	return nil
+}
+
 func (r *Report) Summary() [][]string {
 
 	header := fmt.Sprint("[", r.Url, "](fg-bold)")
+	if r.Name != "" {
+		header = fmt.Sprint("[", r.Name, "](fg-bold)")
+	}
 	if !r.Active {
 		header = fmt.Sprint(header, " [(sleeping)](fg-yellow)")
 	}
-	summary := [][]string{
-		{
-			header,
-			"[" + r.MediumTerm.Period + "](fg-bold)",
-			formatMs(r.MediumTerm.AvgRes, 100.),
-			formatMs(r.MediumTerm.MaxRes, 800.),
-			formatShare(r.MediumTerm.Availability, 0.8, 1.),
-			formatShare(r.MediumTerm.Share2XX, 0.8, 1.),
-			formatShare(r.MediumTerm.Share5XX, 0., 0.05),
-			formatShare(r.MediumTerm.Share4XX, 0., 0.05),
-			formatShare(r.MediumTerm.UnsuccessfulRate, 0, 0.05),
-		},
-		{
-			fmt.Sprint("check interval: [", r.CheckInterval, " ms](fg-bold)"),
-			"[" + r.LongTerm.Period + "](fg-bold)",
-			formatMs(r.LongTerm.AvgRes, 100.),
-			formatMs(r.LongTerm.MaxRes, 800.),
-			formatShare(r.LongTerm.Availability, 0.8, 1.),
-			formatShare(r.LongTerm.Share2XX, 0.8, 1.),
-			formatShare(r.LongTerm.Share5XX, 0., 0.05),
-			formatShare(r.LongTerm.Share4XX, 0., 0.05),
-			formatShare(r.LongTerm.UnsuccessfulRate, 0, 0.05),
-		},
+	// First column: the website, then details on it
+	details := []string{header, fmt.Sprint("check interval: [", r.CheckInterval, " ms](fg-bold)")}
+	details = append(details, r.certificateSummary()...)
+
+	rows := len(r.Measures)
+	if len(details) > rows {
+		rows = len(details)
+	}
+	summary := make([][]string, 0, rows)
+	for idx := 0; idx < rows; idx++ {
+		var first string
+		if idx < len(details) {
+			first = details[idx]
+		}
+		if idx >= len(r.Measures) {
+			summary = append(summary, []string{first, "", "", "", "", "", "", "", "", "", "", "", "", "", ""})
+			continue
+		}
+		m := r.Measures[idx]
+		summary = append(summary, []string{
+			first,
+			"[" + m.Period + "](fg-bold)",
+			formatMs(m.AvgRes, 100.),
+			formatMs(m.MaxRes, 800.),
+			formatMs(m.P50Res, 100.),
+			formatMs(m.P90Res, 300.),
+			formatMs(m.P95Res, 500.),
+			formatMs(m.P99Res, 800.),
+			formatShare(m.Availability, 0.8, 1.),
+			formatShare(m.Share2XX, 0.8, 1.),
+			formatShare(m.Share5XX, 0., 0.05),
+			formatShare(m.Share4XX, 0., 0.05),
+			formatShare(m.UnsuccessfulRate, 0, 0.05) + formatFailures(m.Failures) + formatRetries(m.Unconfirmed, m.Retries),
+			formatPhases(m.Phases, false),
+			formatPhases(m.Phases, true),
+		})
 	}
 
 	return summary
 }
 
+// Lines describing the certificate: expiry and issuer, SANs and validation error, if any
+func (r *Report) certificateSummary() []string {
+	cert := r.Certificate
+	if cert == nil {
+		return nil
+	}
+	color := "fg-green"
+	switch {
+	case cert.DaysToExpiry < 7:
+		color = "fg-red"
+	case cert.DaysToExpiry < 30:
+		color = "fg-yellow"
+	}
+	lines := []string{
+		fmt.Sprint("cert: [", cert.DaysToExpiry, " days](", color, ") by ", cert.Issuer),
+		fmt.Sprint("SAN: ", strings.Join(cert.SANs, " ")),
+	}
+	if cert.ChainError != "" {
+		lines = append(lines, fmt.Sprint("[", cert.ChainError, "](fg-red)"))
+	}
+	return lines
+}
+
 func (m *Measures) Update(aggregator *Aggregator) error {
 	availability, err := aggregator.GetAvailability()
 	if err != nil {
@@ -121,6 +208,30 @@ func (m *Measures) Update(aggregator *Aggregator) error {
 	}
 	m.MaxRes = maxRes
 
+	p50Res, err := aggregator.GetPercentileResTime(50)
+	if err != nil {
+		return err
+	}
+	m.P50Res = p50Res
+
+	p90Res, err := aggregator.GetPercentileResTime(90)
+	if err != nil {
+		return err
+	}
+	m.P90Res = p90Res
+
+	p95Res, err := aggregator.GetPercentileResTime(95)
+	if err != nil {
+		return err
+	}
+	m.P95Res = p95Res
+
+	p99Res, err := aggregator.GetPercentileResTime(99)
+	if err != nil {
+		return err
+	}
+	m.P99Res = p99Res
+
 	share5XX, err := aggregator.GetStatusAgg(5)
 	if err != nil {
 		return err
@@ -155,9 +266,140 @@ func (m *Measures) Update(aggregator *Aggregator) error {
 	}
 
 	m.UnsuccessfulRate = float32(errCount) / float32(errCount+count)
+
+	assertionCount, err := aggregator.GetAssertionFailureCount()
+	if err != nil {
+		return err
+	}
+	m.AssertionRate = float32(assertionCount) / float32(errCount+count)
+
+	m.Failures, err = aggregator.GetFailureCounts()
+	if err != nil {
+		return err
+	}
+	m.Unconfirmed, err = aggregator.GetUnconfirmedCount()
+	if err != nil {
+		return err
+	}
+	m.Retries, err = aggregator.GetRetryCount()
+	if err != nil {
+		return err
+	}
+
+	if len(m.Phases) != PHASE_COUNT {
+		m.Phases = newPhaseMeasures()
+	}
+	for phase := range m.Phases {
+		m.Phases[phase].Avg, err = aggregator.GetAvgPhaseTime(phase)
+		if err != nil {
+			return err
+		}
+		m.Phases[phase].Max, err = aggregator.GetMaxPhaseTime(phase)
+		if err != nil {
+			return err
+		}
+	}
+
+	m.clearUndefined()
 	return nil
 }
 
+// Replace values undefined for lack of data (0 / 0) with -1, read as "collecting..."
+func (m *Measures) clearUndefined() {
+	for _, value := range []*float32{
+		&m.AvgRes, &m.MaxRes, &m.P50Res, &m.P90Res, &m.P95Res, &m.P99Res,
+		&m.Share2XX, &m.Share3XX, &m.Share4XX, &m.Share5XX,
+		&m.Availability, &m.UnsuccessfulRate, &m.AssertionRate,
+	} {
+		if math.IsNaN(float64(*value)) {
+			*value = -1.
+		}
+	}
+	for phase := range m.Phases {
+		for _, value := range []*float32{&m.Phases[phase].Avg, &m.Phases[phase].Max} {
+			if math.IsNaN(float64(*value)) {
+				*value = -1.
+			}
+		}
+	}
+}
+
+// Plain text version of the measures, without termui markup
+func (m Measures) String() string {
+	return fmt.Sprint(
+		m.Period,
+		": availability ", plainShare(m.Availability),
+		", avg ", plainMs(m.AvgRes),
+		", max ", plainMs(m.MaxRes),
+		", p50 ", plainMs(m.P50Res),
+		", p90 ", plainMs(m.P90Res),
+		", p95 ", plainMs(m.P95Res),
+		", p99 ", plainMs(m.P99Res),
+		", 2XX ", plainShare(m.Share2XX),
+		", 3XX ", plainShare(m.Share3XX),
+		", 4XX ", plainShare(m.Share4XX),
+		", 5XX ", plainShare(m.Share5XX),
+		", unsuccessful ", plainShare(m.UnsuccessfulRate), formatFailures(m.Failures), formatRetries(m.Unconfirmed, m.Retries),
+		plainPhases(m.Phases),
+	)
+}
+
+// Count of each category of failure, most frequent first, eg " (timeout 3, dns 1)"
+func formatFailures(failures map[string]int) string {
+	sorted := sortedFailures(failures)
+	if len(sorted) == 0 {
+		return ""
+	}
+	counts := make([]string, len(sorted))
+	for idx, failure := range sorted {
+		counts[idx] = fmt.Sprint(failure, " ", failures[failure])
+	}
+	return " (" + strings.Join(counts, ", ") + ")"
+}
+
+// Unconfirmed checks and retried attempts, if any, eg " [2 unconfirmed, 5 retries]"
+func formatRetries(unconfirmed int, retries int) string {
+	counts := make([]string, 0, 2)
+	if unconfirmed > 0 {
+		counts = append(counts, fmt.Sprint(unconfirmed, " unconfirmed"))
+	}
+	if retries > 0 {
+		counts = append(counts, fmt.Sprint(retries, " retries"))
+	}
+	if len(counts) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(counts, ", ") + "]"
+}
+
+// Average / maximum duration of each phase, eg ", dns 1.2/3.4 ms, connect ..."
+func plainPhases(phases []PhaseMeasures) string {
+	var out strings.Builder
+	for _, phase := range phases {
+		if phase.Avg < 0. {
+			continue
+		}
+		fmt.Fprint(&out, ", ", phase.Phase, " ", math.Floor(float64(phase.Avg)*100)/100, "/", math.Floor(float64(phase.Max)*100)/100, " ms")
+	}
+	return out.String()
+}
+
+// Average or maximum duration of each phase, separated by slashes, eg "1/10/25/80/5 ms"
+func formatPhases(phases []PhaseMeasures, max bool) string {
+	values := make([]string, 0, len(phases))
+	for _, phase := range phases {
+		value := phase.Avg
+		if max {
+			value = phase.Max
+		}
+		if value < 0. {
+			return "collecting..."
+		}
+		values = append(values, fmt.Sprint(math.Floor(float64(value))))
+	}
+	return strings.Join(values, "/") + " ms"
+}
+
 func formatShare(value float32, low float32, high float32) string {
 	if value < 0. {
 		return "collecting..."
@@ -185,3 +427,17 @@ func formatMs(value float32, high float32) string {
 	}
 	return str
 }
+
+func plainShare(value float32) string {
+	if value < 0. || math.IsNaN(float64(value)) {
+		return "n/a"
+	}
+	return fmt.Sprint(math.Floor(float64(value)*1000)/10, " %")
+}
+
+func plainMs(value float32) string {
+	if value < 0. || math.IsNaN(float64(value)) {
+		return "n/a"
+	}
+	return fmt.Sprint(math.Floor(float64(value)*100)/100, " ms")
+}
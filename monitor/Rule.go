@@ -0,0 +1,224 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Metrics alert rules can be evaluated on
+const (
+	METRIC_AVAILABILITY = "availability"
+	METRIC_AVG_LATENCY  = "avg_latency"
+	METRIC_MAX_LATENCY  = "max_latency"
+	METRIC_PERCENTILE   = "percentile" // Latency at AlertRule.Percentile
+	METRIC_ERROR_RATE   = "error_rate"
+	METRIC_5XX_SHARE    = "5xx_share"
+)
+
+// Comparisons, ordered so that 2-characters operators are matched first when parsing
+var comparisons = []string{"<=", ">=", "<", ">"}
+
+// An alert is raised when Metric compares to Threshold, and a recovery alert once it does not anymore
+// Latencies are in milliseconds, rates and shares between 0 and 1
+type AlertRule struct {
+	Metric     string
+	Percentile float64 // Only for METRIC_PERCENTILE
+	Comparison string  // One of <, <=, >, >=
+	Threshold  float32
+	Window     string // Name of the window, the first window of the website if empty
+}
+
+// Alert when availability over the first window (2 min by default) drops to 80% or lower
+func DefaultAlertRules() []AlertRule {
+	return []AlertRule{
+		{Metric: METRIC_AVAILABILITY, Comparison: "<=", Threshold: 0.8},
+	}
+}
+
+// Parse a rule from "<metric><comparison><threshold>[@<window>]", eg "availability<0.995" or "p99>800@medium"
+func ParseAlertRule(str string) (AlertRule, error) {
+	var rule AlertRule
+	str = strings.TrimSpace(str)
+	if at := strings.LastIndex(str, "@"); at >= 0 {
+		rule.Window = strings.TrimSpace(str[at+1:])
+		str = str[:at]
+	}
+	for _, comparison := range comparisons {
+		idx := strings.Index(str, comparison)
+		if idx < 0 {
+			continue
+		}
+		rule.Metric = strings.TrimSpace(str[:idx])
+		rule.Comparison = comparison
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(str[idx+len(comparison):]), 32)
+		if err != nil {
+			return rule, errors.New("INVALID ALERT RULE THRESHOLD IN " + str)
+		}
+		rule.Threshold = float32(threshold)
+		break
+	}
+	if rule.Comparison == "" {
+		return rule, errors.New("INVALID ALERT RULE " + str + ": MISSING COMPARISON")
+	}
+	// pNN is a shorthand for percentile NN
+	if len(rule.Metric) > 1 && rule.Metric[0] == 'p' {
+		percentile, err := strconv.ParseFloat(rule.Metric[1:], 64)
+		if err == nil {
+			rule.Metric = METRIC_PERCENTILE
+			rule.Percentile = percentile
+		}
+	}
+	return rule, rule.Validate()
+}
+
+// Check metric and comparison are known
+func (r AlertRule) Validate() error {
+	switch r.Metric {
+	case METRIC_AVAILABILITY, METRIC_AVG_LATENCY, METRIC_MAX_LATENCY, METRIC_ERROR_RATE, METRIC_5XX_SHARE:
+	case METRIC_PERCENTILE:
+		if r.Percentile <= 0 || r.Percentile > 100 {
+			return errors.New("INVALID ALERT RULE PERCENTILE " + fmt.Sprint(r.Percentile))
+		}
+	default:
+		return errors.New("UNKNOWN ALERT RULE METRIC " + r.Metric)
+	}
+	switch r.Comparison {
+	case "<", "<=", ">", ">=":
+	default:
+		return errors.New("UNKNOWN ALERT RULE COMPARISON " + r.Comparison)
+	}
+	return nil
+}
+
+// Check the rule is valid, and evaluated on one of windows
+func (r AlertRule) ValidateFor(windows []Window) error {
+	err := r.Validate()
+	if err != nil || r.Window == "" {
+		return err
+	}
+	for _, window := range windows {
+		if window.Name == r.Window {
+			return nil
+		}
+	}
+	return errors.New("UNKNOWN ALERT RULE WINDOW " + r.Window)
+}
+
+// Name of the metric, eg "p99" or "availability"
+func (r AlertRule) MetricName() string {
+	if r.Metric == METRIC_PERCENTILE {
+		return "p" + strconv.FormatFloat(r.Percentile, 'f', -1, 64)
+	}
+	return r.Metric
+}
+
+func (r AlertRule) String() string {
+	if r.Window == "" {
+		return fmt.Sprint(r.MetricName(), r.Comparison, r.Threshold)
+	}
+	return fmt.Sprint(r.MetricName(), r.Comparison, r.Threshold, "@", r.Window)
+}
+
+// Whether value triggers the rule
+func (r AlertRule) triggered(value float32) bool {
+	switch r.Comparison {
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	}
+	return false
+}
+
+// Current value of the rule's metric, false if undefined for lack of data
+// Unconfirmed checks are left out, so that they do not trigger rules before being confirmed
+// Must be called with the aggregator's mutex held
+func (a *Aggregator) metric(rule AlertRule) (float32, bool) {
+	var value float32
+	switch rule.Metric {
+	case METRIC_AVAILABILITY:
+		value = float32(a.availableCount) / float32(a.count+a.errorCount)
+	case METRIC_AVG_LATENCY:
+		confirmed := a.count - (a.unconfirmed - a.unconfirmedErr)
+		value = float32(a.sumResTime-a.unconfirmedSum) / float32(confirmed)
+	case METRIC_MAX_LATENCY:
+		if a.confirmedTimes.len() == 0 {
+			return 0, false
+		}
+		value = float32(math.Floor(a.confirmedTimes.percentile(100).Seconds() * 1000))
+	case METRIC_PERCENTILE:
+		if a.confirmedRes.len() == 0 {
+			return 0, false
+		}
+		value = float32(math.Floor(a.confirmedRes.percentile(rule.Percentile).Seconds() * 1000))
+	case METRIC_ERROR_RATE:
+		value = float32(a.errorCount-a.unconfirmedErr) / float32(a.count+a.errorCount)
+	case METRIC_5XX_SHARE:
+		value = float32(a.statusAgg[5]-a.unconfirmed5xx) / float32(a.responseCount()-a.unconfirmedRes)
+	default:
+		return 0, false
+	}
+	if math.IsNaN(float64(value)) {
+		return 0, false
+	}
+	return value, true
+}
+
+// Evaluate rules after an aggregation, and build alerts for rules changing state
+// Must be called with the aggregator's mutex held
+func (a *Aggregator) evaluateRules() []Alert {
+	alerts := make([]Alert, 0)
+	for idx, rule := range a.rules {
+		value, defined := a.metric(rule)
+		if !defined {
+			continue
+		}
+		triggered := rule.triggered(value)
+		if triggered == a.ruleStatus[idx] {
+			continue
+		}
+		a.ruleStatus[idx] = triggered
+		alerts = append(alerts, a.ruleAlert(rule, value, triggered))
+	}
+
+	a.AlertStatus = false
+	for _, status := range a.ruleStatus {
+		a.AlertStatus = a.AlertStatus || status
+	}
+	return alerts
+}
+
+func (a *Aggregator) ruleAlert(rule AlertRule, value float32, triggered bool) Alert {
+	timestamp := a.first.Timestamp
+	formattedTimestamp := formatTime(timestamp)
+	alert := Alert{
+		Init:      true,
+		Url:       a.website,
+		Timestamp: timestamp,
+		Value:     value,
+		Metric:    rule.MetricName(),
+		Kind:      ALERT_RECOVERED,
+	}
+	if triggered {
+		alert.Kind = ALERT_DOWN
+	}
+
+	switch {
+	case rule.Metric == METRIC_AVAILABILITY && triggered:
+		alert.Message = fmt.Sprint("[Website ", a.website, " is down !\n Availability: ", value, "%; time: ", formattedTimestamp, "](fg-red)")
+	case rule.Metric == METRIC_AVAILABILITY:
+		alert.Message = fmt.Sprint("[Website ", a.website, " is up again !\n Availability: ", value, "%; time: ", formattedTimestamp, "](fg-green)")
+	case triggered:
+		alert.Message = fmt.Sprint("[Website ", a.website, " ", rule.MetricName(), " is ", value, " (", rule.Comparison, " ", rule.Threshold, ") over ", a.duration, " !\n time: ", formattedTimestamp, "](fg-red)")
+	default:
+		alert.Message = fmt.Sprint("[Website ", a.website, " ", rule.MetricName(), " is back to ", value, " over ", a.duration, " !\n time: ", formattedTimestamp, "](fg-green)")
+	}
+	return alert
+}
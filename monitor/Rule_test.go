@@ -0,0 +1,91 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAlertRule(t *testing.T) {
+	cases := map[string]AlertRule{
+		"availability<0.995":      {Metric: METRIC_AVAILABILITY, Comparison: "<", Threshold: 0.995},
+		"avg_latency >= 300@long": {Metric: METRIC_AVG_LATENCY, Comparison: ">=", Threshold: 300, Window: WINDOW_LONG},
+		"p99>800@medium":          {Metric: METRIC_PERCENTILE, Percentile: 99, Comparison: ">", Threshold: 800, Window: WINDOW_MEDIUM},
+		"5xx_share>0.05":          {Metric: METRIC_5XX_SHARE, Comparison: ">", Threshold: 0.05},
+	}
+	for str, expected := range cases {
+		rule, err := ParseAlertRule(str)
+		if err != nil {
+			t.Error("Unexpected error parsing", str, ":", err)
+		}
+		if rule != expected {
+			t.Error("Parsed", str, "as", rule, "expected", expected)
+		}
+	}
+
+	for _, str := range []string{"availability", "uptime<0.9", "availability<high", "p0>10"} {
+		if _, err := ParseAlertRule(str); err == nil {
+			t.Error("Parsing", str, "should return a non-nil error")
+		}
+	}
+
+	rule, _ := ParseAlertRule("error_rate>0.1@week")
+	if rule.ValidateFor(DefaultWindows()) == nil {
+		t.Error("Rule on an unknown window should return a non-nil error")
+	}
+	if rule.ValidateFor([]Window{{"week", 7 * 24 * time.Hour}}) != nil {
+		t.Error("Rule on a known window should be valid")
+	}
+}
+
+func TestAggregator_CustomRules(t *testing.T) {
+	rules := []AlertRule{
+		{Metric: METRIC_AVAILABILITY, Comparison: "<", Threshold: 0.995, Window: WINDOW_SHORT},
+		{Metric: METRIC_MAX_LATENCY, Comparison: ">", Threshold: 70, Window: WINDOW_MEDIUM},
+	}
+	agg := NewAggregatorsWithRules("http://www.example.com", DefaultWindows(), rules)
+
+	// Log #3 (500, 80 ms) triggers both rules, each on its own window
+	expectedShort := []AlertKind{ALERT_INFO, ALERT_INFO, ALERT_INFO, ALERT_DOWN, ALERT_INFO, ALERT_INFO, ALERT_RECOVERED, ALERT_DOWN}
+	expectedMedium := []AlertKind{ALERT_INFO, ALERT_INFO, ALERT_INFO, ALERT_DOWN, ALERT_INFO, ALERT_INFO, ALERT_INFO, ALERT_INFO}
+
+	for idx, log := range pingLogs {
+		e := QueueElement{Timestamp: log.Time, Value: log}
+		for _, window := range []struct {
+			agg      *Aggregator
+			expected []AlertKind
+		}{{agg.Get(WINDOW_SHORT), expectedShort}, {agg.Get(WINDOW_MEDIUM), expectedMedium}} {
+			alerts, err := window.agg.Aggregate(e)
+			if err != nil {
+				t.Error("An error occurred while aggregating log:", err)
+			}
+			kind := ALERT_INFO
+			if len(alerts) == 1 {
+				kind = alerts[0].Kind
+			} else if len(alerts) > 1 {
+				t.Error("Log #", idx, "raised too many alerts:", alerts)
+			}
+			if kind != window.expected[idx] {
+				t.Error("Log #", idx, "on", window.agg.GetDuration(), "raised", kind, "expected", window.expected[idx])
+			}
+		}
+	}
+	if agg.Get(WINDOW_LONG).AlertStatus {
+		t.Error("Long window has no rule and should not be alerting")
+	}
+}
+
+func TestAggregator_LatencyAlertMessage(t *testing.T) {
+	rules := []AlertRule{{Metric: METRIC_PERCENTILE, Percentile: 90, Comparison: ">", Threshold: 100}}
+	agg := NewAggregatorsWithRules("http://www.example.com", DefaultWindows(), rules)
+	now := time.Now()
+	alerts, _ := agg.Get(WINDOW_SHORT).Aggregate(QueueElement{
+		Timestamp: now,
+		Value:     &PingLog{Status: 200, ResponseTime: 150 * time.Millisecond, Time: now},
+	})
+	if len(alerts) != 1 {
+		t.Fatal("Expected 1 alert, got", alerts)
+	}
+	if alerts[0].Metric != "p90" || alerts[0].Value != 150 || alerts[0].Kind != ALERT_DOWN {
+		t.Error("Alert does not match expectation:", alerts[0])
+	}
+}
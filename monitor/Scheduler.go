@@ -0,0 +1,343 @@
+package monitor
+
+import (
+	heapq "container/heap"
+	"context"
+	"errors"
+	"math/rand"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Checks running at once, for schedulers not setting theirs
+const DEFAULT_MAX_CONCURRENCY = 64
+
+type SchedulerOptions struct {
+	MaxConcurrency  int           // Checks running at once, DEFAULT_MAX_CONCURRENCY if 0
+	HostConcurrency int           // Checks running at once per host, unlimited if 0
+	Jitter          time.Duration // Maximum random delay of the first check of a website, capped to its check interval
+}
+
+// Runs the checks of pingers with a pool of workers, instead of a goroutine and a ticker per pinger
+// Pingers are queued by the time their next check is due, and rescheduled once it completes,
+// so that a slow website delays its own checks only
+type Scheduler struct {
+	options SchedulerOptions
+	queue   scheduleQueue
+	entries map[*Pinger]*scheduleEntry
+	waiting map[string][]*scheduleEntry // Due entries of hosts at their limit, oldest first
+	hosts   map[string]int              // Checks running per host
+	running int
+	jobs    chan *scheduleEntry
+	wake    chan bool
+	stop    chan bool
+	started bool
+	stopped bool
+	random  *rand.Rand
+	mutex   sync.Mutex
+}
+
+type scheduleEntry struct {
+	pinger   *Pinger
+	ctx      context.Context // Context of the checks, cancelled when the pinger is stopped
+	host     string
+	interval time.Duration
+	due      time.Time
+	index    int       // Index in the queue, -1 when it is not queued
+	removed  bool      // Removed while its check was running or waiting for its host
+	checking chan bool // Closed once the running check completed, nil when none is running
+}
+
+func NewScheduler(options SchedulerOptions) (*Scheduler, error) {
+	if options.MaxConcurrency < 0 || options.HostConcurrency < 0 || options.Jitter < 0 {
+		return nil, errors.New("SCHEDULER CONCURRENCY AND JITTER MUST NOT BE NEGATIVE")
+	}
+	if options.MaxConcurrency == 0 {
+		options.MaxConcurrency = DEFAULT_MAX_CONCURRENCY
+	}
+	return &Scheduler{
+		options: options,
+		queue:   make(scheduleQueue, 0),
+		entries: make(map[*Pinger]*scheduleEntry),
+		waiting: make(map[string][]*scheduleEntry),
+		hosts:   make(map[string]int),
+		jobs:    make(chan *scheduleEntry, options.MaxConcurrency),
+		wake:    make(chan bool, 1),
+		stop:    make(chan bool),
+		random:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// Start the dispatcher and the workers, a stopped scheduler cannot be started again
+func (s *Scheduler) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.started || s.stopped {
+		return
+	}
+	s.started = true
+	for idx := 0; idx < s.options.MaxConcurrency; idx++ {
+		go s.work()
+	}
+	go s.dispatch()
+}
+
+// Stop dispatching checks, running ones complete but are not rescheduled
+// Checks handed to workers but not started yet are released without running
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stopped {
+		return
+	}
+	s.stopped = true
+	close(s.stop)
+	// Workers may have returned, leaving these waited for by their pingers
+	for {
+		select {
+		case entry := <-s.jobs:
+			s.release(entry)
+		default:
+			return
+		}
+	}
+}
+
+// Schedule the checks of pinger until ctx is cancelled, the first one after a random jitter
+func (s *Scheduler) add(pinger *Pinger, ctx context.Context) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, exists := s.entries[pinger]; exists {
+		return
+	}
+	interval := time.Duration(pinger.Interval) * time.Millisecond
+	if interval <= 0 {
+		// Rejected by Orchestrator.Register, only pingers built by hand end up here
+		return
+	}
+	entry := &scheduleEntry{
+		pinger:   pinger,
+		ctx:      ctx,
+		host:     hostOf(pinger.Url),
+		interval: interval,
+		due:      time.Now().Add(s.jitter(interval)),
+	}
+	s.entries[pinger] = entry
+	heapq.Push(&s.queue, entry)
+	s.notify()
+}
+
+// Stop scheduling the checks of pinger
+// Returns a channel closed once its running check completed, nil if none is running or the scheduler is stopped
+func (s *Scheduler) remove(pinger *Pinger) chan bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	entry, exists := s.entries[pinger]
+	if !exists {
+		return nil
+	}
+	if s.stopped {
+		delete(s.entries, pinger)
+		entry.removed = true
+		return nil
+	}
+	delete(s.entries, pinger)
+	entry.removed = true
+	if entry.index >= 0 {
+		heapq.Remove(&s.queue, entry.index)
+	}
+	waiting := s.waiting[entry.host]
+	for idx, other := range waiting {
+		if other == entry {
+			s.waiting[entry.host] = append(waiting[:idx:idx], waiting[idx+1:]...)
+			break
+		}
+	}
+	return entry.checking
+}
+
+// Random delay, shorter than the interval and the jitter
+func (s *Scheduler) jitter(interval time.Duration) time.Duration {
+	max := s.options.Jitter
+	if interval < max {
+		max = interval
+	}
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(s.random.Int63n(int64(max)))
+}
+
+// Wake the dispatcher up, must be called with the mutex held
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- true:
+	default:
+	}
+}
+
+func (s *Scheduler) dispatch() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		s.mutex.Lock()
+		wait := s.dispatchDue(time.Now())
+		s.mutex.Unlock()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if wait >= 0 {
+			timer.Reset(wait)
+		}
+		select {
+		case <-timer.C:
+		case <-s.wake:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Send due checks to the workers, within the concurrency limits
+// Returns the time until the next check is due, -1 if there is none or no worker is available
+// Must be called with the mutex held
+func (s *Scheduler) dispatchDue(now time.Time) time.Duration {
+	if s.stopped {
+		return -1
+	}
+	for host, waiting := range s.waiting {
+		for len(waiting) > 0 && s.running < s.options.MaxConcurrency && !s.hostFull(host) {
+			s.run(waiting[0])
+			waiting = waiting[1:]
+		}
+		if len(waiting) == 0 {
+			delete(s.waiting, host)
+		} else {
+			s.waiting[host] = waiting
+		}
+	}
+	for s.queue.Len() > 0 && s.running < s.options.MaxConcurrency {
+		entry := s.queue[0]
+		if entry.due.After(now) {
+			return entry.due.Sub(now)
+		}
+		heapq.Pop(&s.queue)
+		if entry.ctx.Err() != nil {
+			s.drop(entry)
+			continue
+		}
+		if s.hostFull(entry.host) {
+			s.waiting[entry.host] = append(s.waiting[entry.host], entry)
+			continue
+		}
+		s.run(entry)
+	}
+	return -1
+}
+
+func (s *Scheduler) hostFull(host string) bool {
+	return s.options.HostConcurrency > 0 && s.hosts[host] >= s.options.HostConcurrency
+}
+
+// Hand entry to a worker, must be called with the mutex held
+// The jobs channel holds as many entries as there are workers, so this does not block
+func (s *Scheduler) run(entry *scheduleEntry) {
+	entry.checking = make(chan bool)
+	s.running++
+	s.hosts[entry.host]++
+	s.jobs <- entry
+}
+
+func (s *Scheduler) work() {
+	for {
+		select {
+		case entry := <-s.jobs:
+			entry.pinger.send(entry.ctx, entry.pinger.Check(entry.ctx))
+			s.done(entry)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Release the limits held by the check of entry, and schedule its next check
+func (s *Scheduler) done(entry *scheduleEntry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.release(entry)
+	if !entry.removed && entry.ctx.Err() != nil {
+		s.drop(entry)
+	}
+	if !entry.removed {
+		// Checks missed while this one ran are skipped, as a ticker would
+		now := time.Now()
+		entry.due = entry.due.Add(entry.interval)
+		for !entry.due.After(now) {
+			entry.due = entry.due.Add(entry.interval)
+		}
+		heapq.Push(&s.queue, entry)
+	}
+	s.notify()
+}
+
+// Release the limits held by the check of entry, and whoever waits for it
+// Must be called with the mutex held
+func (s *Scheduler) release(entry *scheduleEntry) {
+	s.running--
+	s.hosts[entry.host]--
+	if s.hosts[entry.host] == 0 {
+		delete(s.hosts, entry.host)
+	}
+	close(entry.checking)
+	entry.checking = nil
+}
+
+// Forget entry, whose context was cancelled without its pinger being removed
+// Must be called with the mutex held
+func (s *Scheduler) drop(entry *scheduleEntry) {
+	delete(s.entries, entry.pinger)
+	entry.removed = true
+}
+
+// Host the concurrency limit of url applies to, eg example.com for https://example.com:8443/health
+func hostOf(raw string) string {
+	target, err := url.Parse(raw)
+	if err != nil || target.Host == "" {
+		return strings.ToLower(raw)
+	}
+	return strings.ToLower(target.Hostname())
+}
+
+// Entries ordered by due time, see container/heap
+type scheduleQueue []*scheduleEntry
+
+func (q scheduleQueue) Len() int { return len(q) }
+
+func (q scheduleQueue) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+
+func (q scheduleQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *scheduleQueue) Push(x interface{}) {
+	entry := x.(*scheduleEntry)
+	entry.index = len(*q)
+	*q = append(*q, entry)
+}
+
+func (q *scheduleQueue) Pop() interface{} {
+	old := *q
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	entry.index = -1
+	*q = old[:len(old)-1]
+	return entry
+}
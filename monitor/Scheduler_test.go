@@ -0,0 +1,275 @@
+package monitor
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Checker taking delay, recording the most checks running at once, overall and per host
+type concurrencyChecker struct {
+	host    string
+	delay   time.Duration
+	tracker *concurrencyTracker
+}
+
+type concurrencyTracker struct {
+	running map[string]int
+	max     map[string]int
+	total   int
+	maxAll  int
+	checks  map[string]int
+	mutex   sync.Mutex
+}
+
+func newConcurrencyTracker() *concurrencyTracker {
+	return &concurrencyTracker{running: make(map[string]int), max: make(map[string]int), checks: make(map[string]int)}
+}
+
+func (c *concurrencyChecker) Check(ctx context.Context) PingLog {
+	t := c.tracker
+	t.mutex.Lock()
+	t.running[c.host]++
+	t.total++
+	t.checks[c.host]++
+	if t.running[c.host] > t.max[c.host] {
+		t.max[c.host] = t.running[c.host]
+	}
+	if t.total > t.maxAll {
+		t.maxAll = t.total
+	}
+	t.mutex.Unlock()
+
+	time.Sleep(c.delay)
+
+	t.mutex.Lock()
+	t.running[c.host]--
+	t.total--
+	t.mutex.Unlock()
+	return PingLog{Time: time.Now(), Website: c.host, Status: 200}
+}
+
+// Scheduler discarding the logs of its pingers, stopped at the end of the test
+func newTestScheduler(t *testing.T, options SchedulerOptions) (*Scheduler, chan PingLog) {
+	scheduler, err := NewScheduler(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(chan PingLog)
+	go func() {
+		for range out {
+		}
+	}()
+	scheduler.Start()
+	t.Cleanup(scheduler.Stop)
+	return scheduler, out
+}
+
+func TestScheduler_Concurrency(t *testing.T) {
+	scheduler, out := newTestScheduler(t, SchedulerOptions{MaxConcurrency: 3, HostConcurrency: 1})
+	tracker := newConcurrencyTracker()
+	for idx := 0; idx < 8; idx++ {
+		host := "a.test"
+		if idx%2 == 1 {
+			host = "b" + strconv.Itoa(idx) + ".test"
+		}
+		url := "http://" + host + "/" + strconv.Itoa(idx)
+		checker := &concurrencyChecker{host: host, delay: 20 * time.Millisecond, tracker: tracker}
+		pinger := NewPinger(out, Website{Url: url, CheckInterval: 50}, checker)
+		pinger.useScheduler(scheduler)
+		pinger.Start(context.Background())
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	if tracker.maxAll > 3 {
+		t.Error("Expected at most 3 checks at once, got", tracker.maxAll)
+	}
+	for host, max := range tracker.max {
+		if max > 1 {
+			t.Error("Expected at most 1 check at once on", host, "got", max)
+		}
+	}
+	if tracker.checks["a.test"] < 2 || tracker.checks["b1.test"] < 2 {
+		t.Error("Expected every host to be checked repeatedly, got", tracker.checks)
+	}
+}
+
+func TestScheduler_SlowWebsite(t *testing.T) {
+	scheduler, out := newTestScheduler(t, SchedulerOptions{MaxConcurrency: 2})
+	tracker := newConcurrencyTracker()
+	slow := NewPinger(out, Website{Url: "http://slow.test", CheckInterval: 10}, &concurrencyChecker{host: "slow.test", delay: 500 * time.Millisecond, tracker: tracker})
+	fast := NewPinger(out, Website{Url: "http://fast.test", CheckInterval: 20}, &concurrencyChecker{host: "fast.test", tracker: tracker})
+	for _, pinger := range []*Pinger{slow, fast} {
+		pinger.useScheduler(scheduler)
+		pinger.Start(context.Background())
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	if tracker.checks["slow.test"] != 1 || tracker.max["slow.test"] != 1 {
+		t.Error("Expected a single check of the slow website, got", tracker.checks["slow.test"])
+	}
+	if tracker.checks["fast.test"] < 8 {
+		t.Error("Expected the fast website not to be delayed, got", tracker.checks["fast.test"], "checks")
+	}
+}
+
+func TestScheduler_JitterAndStop(t *testing.T) {
+	scheduler, out := newTestScheduler(t, SchedulerOptions{Jitter: time.Hour})
+	tracker := newConcurrencyTracker()
+	pingers := make([]*Pinger, 0)
+	now := time.Now()
+	for idx := 0; idx < 20; idx++ {
+		url := "http://jitter.test/" + strconv.Itoa(idx)
+		pinger := NewPinger(out, Website{Url: url, CheckInterval: 100}, &concurrencyChecker{host: url, tracker: tracker})
+		pinger.useScheduler(scheduler)
+		pinger.Start(context.Background())
+		pingers = append(pingers, pinger)
+	}
+
+	scheduler.mutex.Lock()
+	dues := make(map[time.Time]bool)
+	for _, entry := range scheduler.entries {
+		if entry.due.Before(now) || entry.due.After(now.Add(100*time.Millisecond+time.Since(now))) {
+			t.Error("Expected the first check within the interval, got", entry.due.Sub(now))
+		}
+		dues[entry.due] = true
+	}
+	scheduler.mutex.Unlock()
+	if len(dues) < 10 {
+		t.Error("Expected the first checks to be spread, got", len(dues), "distinct times")
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	for _, pinger := range pingers {
+		pinger.Stop()
+		if pinger.IsRunning() {
+			t.Error("Pinger was not stopped")
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	tracker.mutex.Lock()
+	checks := len(tracker.checks)
+	total := 0
+	for _, count := range tracker.checks {
+		total += count
+	}
+	tracker.mutex.Unlock()
+	if checks != 20 {
+		t.Error("Expected every website to be checked, got", checks)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	tracker.mutex.Lock()
+	after := 0
+	for _, count := range tracker.checks {
+		after += count
+	}
+	tracker.mutex.Unlock()
+	if after != total {
+		t.Error("Checks went on after the pingers were stopped:", after-total)
+	}
+	scheduler.mutex.Lock()
+	if len(scheduler.entries) != 0 || scheduler.queue.Len() != 0 {
+		t.Error("Expected stopped pingers to be unscheduled, got", len(scheduler.entries), scheduler.queue.Len())
+	}
+	scheduler.mutex.Unlock()
+}
+
+func TestScheduler_Options(t *testing.T) {
+	_, err := NewScheduler(SchedulerOptions{MaxConcurrency: -1})
+	if err == nil {
+		t.Error("Expected an error for a negative concurrency")
+	}
+	hosts := map[string]string{
+		"https://Example.com:8443/health": "example.com",
+		"tcp://db.example.com:5432":       "db.example.com",
+		"exec://orders-queue":             "orders-queue",
+		"example":                         "example",
+	}
+	for url, expected := range hosts {
+		if hostOf(url) != expected {
+			t.Error("Expected host", expected, "for", url, "got", hostOf(url))
+		}
+	}
+}
+
+func TestScheduler_StopWithQueuedChecks(t *testing.T) {
+	// Checks handed to workers which never run them, as when workers return on stop
+	scheduler, err := NewScheduler(SchedulerOptions{MaxConcurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := newConcurrencyTracker()
+	pingers := make([]*Pinger, 0)
+	for idx := 0; idx < 2; idx++ {
+		url := "http://queued.test/" + strconv.Itoa(idx)
+		pinger := NewPinger(nil, Website{Url: url, CheckInterval: 100}, &concurrencyChecker{host: url, tracker: tracker})
+		pinger.useScheduler(scheduler)
+		pinger.Start(context.Background())
+		pingers = append(pingers, pinger)
+	}
+	scheduler.mutex.Lock()
+	scheduler.dispatchDue(time.Now().Add(time.Second))
+	checking := make([]chan bool, 0)
+	for _, entry := range scheduler.entries {
+		checking = append(checking, entry.checking)
+	}
+	scheduler.mutex.Unlock()
+	if len(scheduler.jobs) != 2 {
+		t.Fatal("Expected 2 queued checks, got", len(scheduler.jobs))
+	}
+
+	scheduler.Stop()
+	for _, done := range checking {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Queued check was not released on stop")
+		}
+	}
+	for _, pinger := range pingers {
+		stopped := make(chan bool)
+		go func() {
+			pinger.Stop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("Pinger.Stop hung after Scheduler.Stop")
+		}
+	}
+
+	// Same with running workers, stopped while checks are due
+	for iteration := 0; iteration < 50; iteration++ {
+		scheduler, out := newTestScheduler(t, SchedulerOptions{MaxConcurrency: 4})
+		pingers := make([]*Pinger, 0)
+		for idx := 0; idx < 8; idx++ {
+			url := "http://queued.test/" + strconv.Itoa(idx)
+			pinger := NewPinger(out, Website{Url: url, CheckInterval: 1}, &concurrencyChecker{host: url, tracker: tracker})
+			pinger.useScheduler(scheduler)
+			pinger.Start(context.Background())
+			pingers = append(pingers, pinger)
+		}
+		time.Sleep(time.Millisecond)
+		scheduler.Stop()
+		stopped := make(chan bool)
+		go func() {
+			for _, pinger := range pingers {
+				pinger.Stop()
+			}
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("Pinger.Stop hung after Scheduler.Stop, iteration", iteration)
+		}
+	}
+}
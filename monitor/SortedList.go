@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Response times kept in ascending order, duplicates allowed
+// Insertion and deletion are in O(n) (binary search + shift),
+// retrieving the k-th smallest value is in O(1)
+type SortedList struct {
+	values []time.Duration
+}
+
+func NewSortedList() SortedList {
+	return SortedList{
+		values: make([]time.Duration, 0),
+	}
+}
+
+func (s *SortedList) insert(value time.Duration) {
+	idx := sort.Search(len(s.values), func(i int) bool { return s.values[i] >= value })
+	s.values = append(s.values, 0)
+	copy(s.values[idx+1:], s.values[idx:])
+	s.values[idx] = value
+}
+
+// Delete one occurrence of value, if any
+func (s *SortedList) delete(value time.Duration) {
+	idx := sort.Search(len(s.values), func(i int) bool { return s.values[i] >= value })
+	if idx == len(s.values) || s.values[idx] != value {
+		return
+	}
+	s.values = append(s.values[:idx], s.values[idx+1:]...)
+}
+
+func (s *SortedList) len() int {
+	return len(s.values)
+}
+
+// Value at percentile p (0 < p <= 100), using the nearest-rank method
+func (s *SortedList) percentile(p float64) time.Duration {
+	rank := int(math.Ceil(float64(len(s.values))*p/100)) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(s.values) {
+		rank = len(s.values) - 1
+	}
+	return s.values[rank]
+}
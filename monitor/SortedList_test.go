@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSortedList_InsertDelete(t *testing.T) {
+	list := NewSortedList()
+	for _, v := range []time.Duration{5, 1, 3, 3, 9, 2} {
+		list.insert(v)
+	}
+	expected := []time.Duration{1, 2, 3, 3, 5, 9}
+	for idx, value := range list.values {
+		if value != expected[idx] {
+			t.Error("Order doesn't match expectation:", list.values)
+			break
+		}
+	}
+
+	list.delete(3)
+	list.delete(9)
+	// Deleting a missing value is a no-op
+	list.delete(42)
+	expected = []time.Duration{1, 2, 3, 5}
+	if list.len() != len(expected) {
+		t.Fatal("Length doesn't match expectation:", list.values)
+	}
+	for idx, value := range list.values {
+		if value != expected[idx] {
+			t.Error("Order doesn't match expectation:", list.values)
+			break
+		}
+	}
+}
+
+func TestSortedList_Percentile(t *testing.T) {
+	list := NewSortedList()
+	for i := 1; i <= 100; i++ {
+		list.insert(time.Duration(i))
+	}
+	cases := map[float64]time.Duration{1: 1, 50: 50, 90: 90, 95: 95, 99: 99, 100: 100}
+	for p, expected := range cases {
+		if actual := list.percentile(p); actual != expected {
+			t.Error("Percentile", p, "actual:", actual, "expected:", expected)
+		}
+	}
+
+	single := NewSortedList()
+	single.insert(7)
+	if single.percentile(50) != 7 || single.percentile(99) != 7 {
+		t.Error("Percentiles of a single value should be that value")
+	}
+}
+
+func TestAggregator_GetPercentileResTime(t *testing.T) {
+	agg := NewAggregators("http://www.example.com")
+
+	expectedP50 := []float32{50., 50., 50., 50., 50., 50., 50., 50.}
+	expectedP99 := []float32{50., 50., 50., 80., 80., 80., 50., 50.}
+
+	for idx, log := range pingLogs {
+		err, _ := agg.Get(WINDOW_SHORT).Add(QueueElement{Timestamp: log.Time, Value: log})
+		if err != nil {
+			t.Error("An error occurred while aggregating log:", err)
+		}
+		p50, err := agg.Get(WINDOW_SHORT).GetPercentileResTime(50)
+		if err != nil {
+			t.Error("An error occurred while retrieving p50:", err)
+		}
+		p99, err := agg.Get(WINDOW_SHORT).GetPercentileResTime(99)
+		if err != nil {
+			t.Error("An error occurred while retrieving p99:", err)
+		}
+		if p50 != expectedP50[idx] {
+			t.Error("Log #", idx, "p50 actual:", p50, "expected:", expectedP50[idx])
+		}
+		if p99 != expectedP99[idx] {
+			t.Error("Log #", idx, "p99 actual:", p99, "expected:", expectedP99[idx])
+		}
+	}
+
+	if _, err := agg.Get(WINDOW_SHORT).GetPercentileResTime(0); err == nil {
+		t.Error("Percentile 0 should return a non-nil error")
+	}
+	empty := NewAggregators("http://www.example.com")
+	if p, _ := empty.Get(WINDOW_SHORT).GetPercentileResTime(50); p != -1. {
+		t.Error("Percentile of an empty aggregator should be -1, got", p)
+	}
+}
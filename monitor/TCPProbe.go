@@ -0,0 +1,74 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"time"
+)
+
+// Checks a TCP port accepts connections, eg tcp://db.example.com:5432
+// The connection is closed as soon as it is established
+type tcpChecker struct {
+	website string
+	address string // host:port
+	timeout time.Duration
+}
+
+func newTCPProbe(out chan<- PingLog, website Website) (Probe, error) {
+	target, err := url.Parse(website.Url)
+	if err != nil {
+		return nil, err
+	}
+	if target.Hostname() == "" || target.Port() == "" {
+		return nil, errors.New("TCP WEBSITES MUST BE tcp://host:port, GOT " + website.Url)
+	}
+	checker := &tcpChecker{
+		website: website.Url,
+		address: target.Host,
+		timeout: checkTimeout(website),
+	}
+	return NewPinger(out, website, checker), nil
+}
+
+// Resolve the host, then connect to its first address
+// The response time covers both, respectively recorded as the DNS and connect phases
+func (t *tcpChecker) Check(ctx context.Context) PingLog {
+	startTime := time.Now()
+	log := PingLog{
+		Time:     startTime,
+		Website:  t.website,
+		Asserted: true,
+	}
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	host, port, err := net.SplitHostPort(t.address)
+	if err != nil {
+		log.Error = err
+		return log
+	}
+	if net.ParseIP(host) == nil {
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		log.Phases[PHASE_DNS] = time.Now().Sub(startTime)
+		if err != nil {
+			log.Error = err
+			log.ResponseTime = time.Now().Sub(startTime)
+			return log
+		}
+		host = addrs[0]
+	}
+
+	connectStart := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	log.Phases[PHASE_CONNECT] = time.Now().Sub(connectStart)
+	log.ResponseTime = time.Now().Sub(startTime)
+	if err != nil {
+		log.Error = err
+		return log
+	}
+	conn.Close()
+	return log
+}
@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestTCPProbe_Open(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	url := "tcp://" + listener.Addr().String()
+	probe, err := NewProbe(nil, Website{Url: url, CheckInterval: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := probe.Check(context.Background())
+	if log.Error != nil {
+		t.Fatal("Unexpected error:", log.Error)
+	}
+	if log.Website != url || log.Status != 0 || !log.Asserted || log.Failure() != "" {
+		t.Error("Expected an available check without status, got", log)
+	}
+	if log.Phases[PHASE_CONNECT] <= 0 || log.ResponseTime < log.Phases[PHASE_CONNECT] {
+		t.Error("Expected the connect time to be recorded, got", log.Phases, log.ResponseTime)
+	}
+
+	counter := CheckCounter{counts: make(map[int]uint64)}
+	counter.add(&log)
+	if counter.GetCounts()[STATUS_NONE] != 1 {
+		t.Error("Expected the check to be counted without status, got", counter.GetCounts())
+	}
+}
+
+func TestTCPProbe_Closed(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	probe, err := NewProbe(nil, Website{Url: "tcp://" + address, CheckInterval: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := probe.Check(context.Background())
+	if log.Error == nil {
+		t.Fatal("Expected the check to fail")
+	}
+	if log.Failure() != FAILURE_REFUSED {
+		t.Error("Expected a refused connection, got", log.Failure(), log.Error)
+	}
+}
+
+func TestTCPProbe_ValidateUrl(t *testing.T) {
+	urls := map[string]bool{
+		"tcp://db.example.com:5432": true,
+		"tcp://db.example.com":      false,
+		"tcp://:5432":               false,
+		"http://example.com":        true,
+	}
+	for url, valid := range urls {
+		_, err := NewProbe(nil, Website{Url: url, CheckInterval: 1000})
+		if (err == nil) != valid {
+			t.Error("Unexpected validation of", url, err)
+		}
+	}
+}
@@ -1,6 +1,23 @@
 package monitor
 
+import "time"
+
 type Website struct {
+	Name          string // Display name, the url if empty
 	Url           string
+	Type          string // Probe type, see RegisterProbe, the scheme of the url if empty
 	CheckInterval int
+	Timeout       time.Duration     // Timeout of a check, the check interval if 0
+	Method        string            // HTTP method, defaults to GET
+	Headers       map[string]string // Additional request headers
+	Body          string            // Request body, sent as is
+	Command       []string          // Command run by exec websites, and its arguments
+	Assertions    Assertions        // Checks performed on responses
+	AlertRules    []AlertRule       // Rules raising alerts, DefaultAlertRules if empty
+	Windows       []Window          // Aggregation windows, DefaultWindows if empty
+	Tags          []string          // Free-form labels, eg the team owning the website
+	CertExpiry    []int             // Days to certificate expiry alerts are raised at, DefaultCertExpiryDays if empty
+	Retries       int               // Attempts made again within a check while unsuccessful
+	RetryDelay    time.Duration     // Delay before each retry, DEFAULT_RETRY_DELAY if 0
+	ConfirmAfter  int               // Consecutive unsuccessful checks before they count as down, 1 if 0
 }
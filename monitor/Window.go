@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Names of the default windows
+const (
+	WINDOW_SHORT  = "short"
+	WINDOW_MEDIUM = "medium"
+	WINDOW_LONG   = "long"
+)
+
+// Bounds of the report refresh interval of a window
+const MIN_REFRESH_INTERVAL = 10 * time.Second
+const MAX_REFRESH_INTERVAL = time.Minute
+
+// A named aggregation window: metrics are computed over the past Duration
+type Window struct {
+	Name     string
+	Duration time.Duration
+}
+
+// 2 min, 10 min and 1 hour windows
+func DefaultWindows() []Window {
+	return []Window{
+		{WINDOW_SHORT, SHORT_INTERVAL},
+		{WINDOW_MEDIUM, MEDIUM_INTERVAL},
+		{WINDOW_LONG, LONG_INTERAVL},
+	}
+}
+
+// Parse windows from "<duration>" or "<name>=<duration>" items, eg ["1m", "daily=24h"]
+// Windows without a name are named after their duration
+func ParseWindows(items []string) ([]Window, error) {
+	windows := make([]Window, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, str := item, item
+		if idx := strings.Index(item, "="); idx >= 0 {
+			name, str = strings.TrimSpace(item[:idx]), strings.TrimSpace(item[idx+1:])
+		}
+		duration, err := time.ParseDuration(str)
+		if err != nil {
+			return nil, errors.New("INVALID WINDOW DURATION " + str)
+		}
+		windows = append(windows, Window{Name: name, Duration: duration})
+	}
+	return windows, ValidateWindows(windows)
+}
+
+// Check windows are not empty, have positive durations and unique names
+func ValidateWindows(windows []Window) error {
+	if len(windows) == 0 {
+		return errors.New("AT LEAST 1 WINDOW IS REQUIRED")
+	}
+	names := make(map[string]bool)
+	for _, window := range windows {
+		if window.Name == "" {
+			return errors.New("WINDOW NAME IS REQUIRED")
+		}
+		if window.Duration <= 0 {
+			return errors.New("WINDOW " + window.Name + " MUST HAVE A POSITIVE DURATION")
+		}
+		if names[window.Name] {
+			return errors.New("DUPLICATE WINDOW " + window.Name)
+		}
+		names[window.Name] = true
+	}
+	return nil
+}
+
+// Interval at which reports on the window are refreshed: 1/60th of its duration, between 10s and 1 min
+// eg every 10s for 10 min, every minute for 1 hour
+func (w Window) Refresh() time.Duration {
+	refresh := w.Duration / 60
+	if refresh < MIN_REFRESH_INTERVAL {
+		return MIN_REFRESH_INTERVAL
+	}
+	if refresh > MAX_REFRESH_INTERVAL {
+		return MAX_REFRESH_INTERVAL
+	}
+	return refresh
+}
+
+// Label of the window in reports, eg "Past 10 min"
+func (w Window) Period() string {
+	d := w.Duration
+	switch {
+	case d%time.Hour == 0 && d == time.Hour:
+		return "Past 1 hour"
+	case d%time.Hour == 0:
+		return fmt.Sprint("Past ", int64(d/time.Hour), " hours")
+	case d%time.Minute == 0:
+		return fmt.Sprint("Past ", int64(d/time.Minute), " min")
+	case d%time.Second == 0:
+		return fmt.Sprint("Past ", int64(d/time.Second), " s")
+	}
+	return fmt.Sprint("Past ", d)
+}
@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWindows(t *testing.T) {
+	windows, err := ParseWindows([]string{"1m", " 5m", "daily=24h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Window{{"1m", time.Minute}, {"5m", 5 * time.Minute}, {"daily", 24 * time.Hour}}
+	if len(windows) != len(expected) {
+		t.Fatal("Windows do not match expectation:", windows)
+	}
+	for idx, window := range windows {
+		if window != expected[idx] {
+			t.Error("Window #", idx, "actual:", window, "expected:", expected[idx])
+		}
+	}
+
+	for _, items := range [][]string{{}, {"soon"}, {"1m", "1m"}, {"-1m"}} {
+		if _, err := ParseWindows(items); err == nil {
+			t.Error("Parsing", items, "should return a non-nil error")
+		}
+	}
+}
+
+func TestWindow_RefreshAndPeriod(t *testing.T) {
+	cases := []struct {
+		window  Window
+		refresh time.Duration
+		period  string
+	}{
+		{Window{"short", 2 * time.Minute}, 10 * time.Second, "Past 2 min"},
+		{Window{"medium", 10 * time.Minute}, 10 * time.Second, "Past 10 min"},
+		{Window{"long", time.Hour}, time.Minute, "Past 1 hour"},
+		{Window{"daily", 24 * time.Hour}, time.Minute, "Past 24 hours"},
+		{Window{"tiny", 30 * time.Second}, 10 * time.Second, "Past 30 s"},
+	}
+	for _, c := range cases {
+		if c.window.Refresh() != c.refresh {
+			t.Error(c.window, "refresh actual:", c.window.Refresh(), "expected:", c.refresh)
+		}
+		if c.window.Period() != c.period {
+			t.Error(c.window, "period actual:", c.window.Period(), "expected:", c.period)
+		}
+	}
+}
+
+func TestOrchestrator_CustomWindows(t *testing.T) {
+	setup()
+	collectAlerts(t)
+
+	windows := []Window{{"1m", time.Minute}, {"5m", 5 * time.Minute}}
+	website := Website{Url: "example", CheckInterval: 100, Windows: windows}
+	err := orchestrator_test.Register(website)
+	if err != nil {
+		t.Fatal("Error while registering website:", err)
+	}
+	agg, _ := orchestrator_test.GetAggregator(website.Url)
+	if len(agg.Aggregators) != 2 || agg.Get("1m").GetDuration() != time.Minute || agg.Get("5m").GetDuration() != 5*time.Minute {
+		t.Error("Aggregators do not match windows:", agg.Windows)
+	}
+	report := orchestrator_test.GetReport(website.Url)
+	if len(report.Measures) != 2 || report.Get("5m").Period != "Past 5 min" {
+		t.Error("Report does not match windows:", report.Measures)
+	}
+
+	now := time.Now()
+	orchestrator_test.AggLog(PingLog{Website: website.Url, Time: now, Status: 200, ResponseTime: 20 * time.Millisecond})
+	err = orchestrator_test.UpdateReport(website.Url, "5m")
+	if err != nil {
+		t.Error("Error while updating report:", err)
+	}
+	// Reports are copies, taken when they are got
+	report = orchestrator_test.GetReport(website.Url)
+	if report.Get("5m").Availability != 1. || report.Get("1m").Availability != -1. {
+		t.Error("Only the 5m window should have been updated:", report.Measures)
+	}
+	if orchestrator_test.UpdateReport(website.Url, "short") == nil {
+		t.Error("Updating an unknown window should return a non-nil error")
+	}
+
+	invalid := Website{Url: "invalid", CheckInterval: 100, Windows: windows, AlertRules: []AlertRule{
+		{Metric: METRIC_AVAILABILITY, Comparison: "<", Threshold: 0.9, Window: "short"},
+	}}
+	if orchestrator_test.Register(invalid) == nil {
+		t.Error("Registering a rule on an unknown window should return a non-nil error")
+	}
+}
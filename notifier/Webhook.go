@@ -0,0 +1,119 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"suricata/monitor"
+	"sync"
+	"time"
+)
+
+// Payload POSTed to webhooks
+type Payload struct {
+	Url          string    `json:"url"`
+	Timestamp    time.Time `json:"timestamp"`
+	Availability *float32  `json:"availability,omitempty"` // Only for availability alerts
+	Metric       string    `json:"metric"`
+	Value        float32   `json:"value"` // Value of the metric
+	State        string    `json:"state"` // "down" or "up"
+	Message      string    `json:"message"`
+}
+
+// Webhook POSTs down and recovered alerts to a list of urls
+// Deliveries are asynchronous, and retried on failure
+type Webhook struct {
+	Urls       []string
+	Retries    int           // Additional attempts after a failed delivery
+	RetryDelay time.Duration // Delay before the first retry, doubled on each retry
+	OnError    func(error)   // Called when a delivery failed after all retries, if not nil
+	client     *http.Client
+	wg         sync.WaitGroup
+}
+
+func NewWebhook(urls []string, timeout time.Duration, retries int) *Webhook {
+	return &Webhook{
+		Urls:       urls,
+		Retries:    retries,
+		RetryDelay: time.Second,
+		client:     &http.Client{Timeout: timeout},
+	}
+}
+
+// Send alert to all webhooks, if it is a down or recovered alert
+func (w *Webhook) Notify(alert monitor.Alert) {
+	if alert.Kind != monitor.ALERT_DOWN && alert.Kind != monitor.ALERT_RECOVERED {
+		return
+	}
+	payload := Payload{
+		Url:       alert.Url,
+		Timestamp: alert.Timestamp,
+		Metric:    alert.Metric,
+		Value:     alert.Value,
+		State:     alert.Kind.String(),
+		Message:   alert.PlainMessage(),
+	}
+	if payload.Metric == "" {
+		payload.Metric = monitor.METRIC_AVAILABILITY
+	}
+	if payload.Metric == monitor.METRIC_AVAILABILITY {
+		payload.Availability = &alert.Value
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	for _, url := range w.Urls {
+		w.wg.Add(1)
+		go func(url string) {
+			defer w.wg.Done()
+			err := w.deliver(url, body)
+			if err != nil {
+				w.fail(err)
+			}
+		}(url)
+	}
+}
+
+// Wait for pending deliveries
+func (w *Webhook) Wait() {
+	w.wg.Wait()
+}
+
+// POST body to url, retrying on failure
+func (w *Webhook) deliver(url string, body []byte) error {
+	var err error
+	delay := w.RetryDelay
+	for attempt := 0; attempt <= w.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		err = w.post(url, body)
+		if err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("WEBHOOK %s FAILED AFTER %d ATTEMPTS: %v", url, w.Retries+1, err)
+}
+
+func (w *Webhook) post(url string, body []byte) error {
+	res, err := w.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return errors.New("UNEXPECTED STATUS " + res.Status)
+	}
+	return nil
+}
+
+func (w *Webhook) fail(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+	}
+}
@@ -0,0 +1,88 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"suricata/monitor"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhook_Notify(t *testing.T) {
+	var mutex sync.Mutex
+	payloads := make([]Payload, 0)
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		attempts++
+		// Fail the first attempt to trigger a retry
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var payload Payload
+		json.NewDecoder(r.Body).Decode(&payload)
+		payloads = append(payloads, payload)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook([]string{server.URL}, time.Second, 2)
+	webhook.RetryDelay = time.Millisecond
+	webhook.OnError = func(err error) {
+		t.Error("Unexpected delivery error:", err)
+	}
+
+	webhook.Notify(monitor.Alert{Url: "http://www.example.com", Message: "Website http://www.example.com is registered for monitoring", Init: true})
+	webhook.Notify(monitor.Alert{
+		Url:       "http://www.example.com",
+		Timestamp: time.Date(2018, 11, 11, 11, 13, 0, 0, time.UTC),
+		Value:     0.5,
+		Message:   "[Website http://www.example.com is down !](fg-red)",
+		Init:      true,
+		Kind:      monitor.ALERT_DOWN,
+	})
+	webhook.Wait()
+
+	if attempts != 2 {
+		t.Error("Expected 2 attempts, got", attempts)
+	}
+	if len(payloads) != 1 {
+		t.Fatal("Expected 1 payload, got", payloads)
+	}
+	payload := payloads[0]
+	if payload.Url != "http://www.example.com" || payload.State != "down" || payload.Metric != "availability" ||
+		payload.Availability == nil || *payload.Availability != 0.5 || payload.Value != 0.5 {
+		t.Error("Payload does not match expectation:", payload)
+	}
+	if payload.Message != "Website http://www.example.com is down !" {
+		t.Error("Message does not match expectation:", payload.Message)
+	}
+}
+
+func TestWebhook_GivesUp(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook([]string{server.URL}, time.Second, 2)
+	webhook.RetryDelay = time.Millisecond
+	var errs []error
+	webhook.OnError = func(err error) {
+		errs = append(errs, err)
+	}
+	webhook.Notify(monitor.Alert{Url: "http://www.example.com", Init: true, Kind: monitor.ALERT_RECOVERED})
+	webhook.Wait()
+
+	if attempts != 3 {
+		t.Error("Expected 3 attempts, got", attempts)
+	}
+	if len(errs) != 1 {
+		t.Error("Expected 1 delivery error, got", errs)
+	}
+}
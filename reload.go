@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"suricata/config"
+	"suricata/monitor"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Outcome of a config reload
+type configReload struct {
+	websites []monitor.Website // Websites of the config file, nil if it could not be applied
+	summary  monitor.ReloadSummary
+	err      error
+}
+
+// Synchronize the orchestrator's websites with the config file at path, once ready is closed,
+// on SIGHUP or when the file's modification time changes, checked every interval (never if 0)
+// websites are the ones loaded at startup. Reloads are applied aside the caller, which must drain alerts,
+// and their outcome sent on the returned channel. The returned function stops watching,
+// waiting for a reload in progress
+func watchConfig(orchestrator *monitor.Orchestrator, path string, websites []monitor.Website, interval time.Duration, ready <-chan bool) (<-chan configReload, func()) {
+	reloads := make(chan configReload)
+	done := make(chan bool)
+	var wg sync.WaitGroup
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	var tick <-chan time.Time
+	var ticker *time.Ticker
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+	modTime := lastModified(path)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer signal.Stop(signals)
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+		select {
+		case <-ready:
+		case <-done:
+			return
+		}
+		previous := websites
+		for {
+			select {
+			case <-signals:
+				modTime = lastModified(path)
+			case <-tick:
+				current := lastModified(path)
+				if current.IsZero() || current.Equal(modTime) {
+					// Missing while being replaced, or unchanged
+					continue
+				}
+				modTime = current
+			case <-done:
+				return
+			}
+
+			reload := configReload{}
+			reload.websites, reload.err = config.Load(path)
+			if reload.err == nil {
+				reload.summary, reload.err = orchestrator.Reload(previous, reload.websites)
+				previous = reload.websites
+			}
+			if reload.err != nil {
+				reload.websites = nil
+			}
+			select {
+			case reloads <- reload:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return reloads, func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// Modification time of the file at path, zero if it cannot be read
+func lastModified(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Describe the changes of a reload
+func (r configReload) String() string {
+	if r.err != nil {
+		return "Failed to reload config: " + r.err.Error()
+	}
+	if r.summary.IsEmpty() {
+		return "Config reloaded, no website changed"
+	}
+	changes := make([]string, 0, 3)
+	if len(r.summary.Added) > 0 {
+		changes = append(changes, "added "+strings.Join(r.summary.Added, " "))
+	}
+	if len(r.summary.Removed) > 0 {
+		changes = append(changes, "removed "+strings.Join(r.summary.Removed, " "))
+	}
+	if len(r.summary.Changed) > 0 {
+		changes = append(changes, "restarted "+strings.Join(r.summary.Changed, " "))
+	}
+	return "Config reloaded: " + strings.Join(changes, ", ")
+}